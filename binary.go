@@ -0,0 +1,359 @@
+package bitset
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The binary encoding of a Dense, version 1, is:
+//
+//	byte 0:      the version number, 1
+//	bytes 1-7:   reserved, must be zero
+//	bytes 8-15:  the capacity in bits, as a little-endian uint64
+//	bytes 16-:   capacity/64 little-endian uint64 words, one per Set64
+//
+// Bit i of word j represents the element 64*j + i. The header is 16 bytes long
+// so that the words are 8-byte aligned whenever the encoding is.
+const (
+	denseVersion    = 1
+	denseHeaderSize = 16
+)
+
+// maxInt is the largest value of an int.
+const maxInt = int(^uint(0) >> 1)
+
+// decodeLimit bounds the memory that decoding a small input can demand. When
+// decoding text, JSON or a run-length encoding, the package returns an error
+// rather than create a Dense whose capacity exceeds decodeLimit, or add more
+// than decodeLimit elements to a Sparse through ranges. It is at most maxInt,
+// so that any capacity within it is an int.
+const decodeLimit = min(1<<32, uint64(maxInt))
+
+var _ interface {
+	io.WriterTo
+	io.ReaderFrom
+} = (*Dense)(nil)
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (s *Dense) MarshalBinary() ([]byte, error) {
+	b := make([]byte, denseHeaderSize+8*len(s.sets))
+	s.putHeader(b)
+	for i, t := range s.sets {
+		binary.LittleEndian.PutUint64(b[denseHeaderSize+8*i:], uint64(t))
+	}
+	return b, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+// It replaces the contents and capacity of s with those encoded in data.
+func (s *Dense) UnmarshalBinary(data []byte) error {
+	data, err := denseWords(data)
+	if err != nil {
+		return err
+	}
+	sets := make([]Set64, len(data)/8)
+	for i := range sets {
+		sets[i] = Set64(binary.LittleEndian.Uint64(data[8*i:]))
+	}
+	s.sets = sets
+	s.rank = nil
+	return nil
+}
+
+// WriteTo implements io.WriterTo. It writes the same encoding as MarshalBinary,
+// without building it in memory first.
+func (s *Dense) WriteTo(w io.Writer) (int64, error) {
+	var buf [4096]byte
+	s.putHeader(buf[:])
+	i := denseHeaderSize
+	var total int64
+	for _, t := range s.sets {
+		if i == len(buf) {
+			n, err := w.Write(buf[:i])
+			total += int64(n)
+			if err != nil {
+				return total, err
+			}
+			i = 0
+		}
+		binary.LittleEndian.PutUint64(buf[i:], uint64(t))
+		i += 8
+	}
+	n, err := w.Write(buf[:i])
+	total += int64(n)
+	return total, err
+}
+
+// ReadFrom implements io.ReaderFrom. It reads an encoding written by WriteTo or
+// MarshalBinary and replaces the contents and capacity of s with it. ReadFrom
+// reads exactly the bytes of the encoding from r and no more.
+func (s *Dense) ReadFrom(r io.Reader) (int64, error) {
+	var buf [4096]byte
+	n, err := io.ReadFull(r, buf[:denseHeaderSize])
+	total := int64(n)
+	if err != nil {
+		return total, unexpectedEOF(err)
+	}
+	nwords, err := parseDenseHeader(buf[:denseHeaderSize])
+	if err != nil {
+		return total, err
+	}
+	// Grow the slice as the words arrive, so that a corrupt header can't
+	// trigger a huge allocation.
+	sets := make([]Set64, 0, minUint64(nwords, len(buf)/8))
+	for remaining := nwords; remaining > 0; {
+		chunk := buf[:8*minUint64(remaining, len(buf)/8)]
+		n, err := io.ReadFull(r, chunk)
+		total += int64(n)
+		if err != nil {
+			return total, unexpectedEOF(err)
+		}
+		for i := 0; i < len(chunk); i += 8 {
+			sets = append(sets, Set64(binary.LittleEndian.Uint64(chunk[i:])))
+		}
+		remaining -= uint64(len(chunk) / 8)
+	}
+	if len(sets) == 0 {
+		sets = nil
+	}
+	s.sets = sets
+	s.rank = nil
+	return total, nil
+}
+
+// denseWords validates the binary encoding of a Dense in data and returns the
+// part holding the words.
+func denseWords(data []byte) ([]byte, error) {
+	if len(data) < denseHeaderSize {
+		return nil, io.ErrUnexpectedEOF
+	}
+	nwords, err := parseDenseHeader(data[:denseHeaderSize])
+	if err != nil {
+		return nil, err
+	}
+	data = data[denseHeaderSize:]
+	if uint64(len(data))/8 != nwords || len(data)%8 != 0 {
+		return nil, fmt.Errorf("bitset: Dense encoding has %d bytes of words, want %d", len(data), nwords*8)
+	}
+	return data, nil
+}
+
+// putHeader writes the header of the binary encoding of s to b.
+func (s *Dense) putHeader(b []byte) {
+	b[0] = denseVersion
+	for i := 1; i < 8; i++ {
+		b[i] = 0
+	}
+	binary.LittleEndian.PutUint64(b[8:], uint64(s.Cap()))
+}
+
+// parseDenseHeader validates the header of a Dense encoding and returns the
+// number of words that follow it.
+func parseDenseHeader(b []byte) (uint64, error) {
+	if b[0] != denseVersion {
+		return 0, fmt.Errorf("bitset: unknown Dense encoding version %d", b[0])
+	}
+	for _, c := range b[1:8] {
+		if c != 0 {
+			return 0, errors.New("bitset: nonzero reserved bytes in Dense encoding")
+		}
+	}
+	capacity := binary.LittleEndian.Uint64(b[8:])
+	if capacity%64 != 0 {
+		return 0, fmt.Errorf("bitset: Dense capacity %d is not a multiple of 64", capacity)
+	}
+	if capacity > uint64(maxInt) {
+		return 0, fmt.Errorf("bitset: Dense capacity %d is too large", capacity)
+	}
+	return capacity / 64, nil
+}
+
+// unexpectedEOF converts io.EOF to io.ErrUnexpectedEOF, for readers that have
+// started reading an encoding.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func minUint64(a uint64, b int) uint64 {
+	if a < uint64(b) {
+		return a
+	}
+	return uint64(b)
+}
+
+// The binary encoding of a Sparse, version 1, preserves the shape of its radix
+// tree. It is:
+//
+//	byte 0:   the version number, 1
+//	bytes 1-: the root node, absent if the set is empty
+//
+// A node is encoded as its shift (one byte), its presence bitmap (four
+// little-endian uint64s, lowest first), and then each of its subnodes in
+// order. A subnode of a node with shift 8 is a set256 leaf, encoded as four
+// little-endian uint64s; otherwise it is a node.
+const sparseVersion = 1
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (s *Sparse) MarshalBinary() ([]byte, error) {
+	b := []byte{sparseVersion}
+	if s.root != nil {
+		b = s.root.appendBinary(b)
+	}
+	return b, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+// It replaces the contents of s with those encoded in data.
+func (s *Sparse) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return io.ErrUnexpectedEOF
+	}
+	if data[0] != sparseVersion {
+		return fmt.Errorf("bitset: unknown Sparse encoding version %d", data[0])
+	}
+	if len(data) == 1 {
+		s.root = nil
+		return nil
+	}
+	d := sparseDecoder{data: data, pos: 1}
+	root, err := d.node(64 - 8)
+	if err != nil {
+		return err
+	}
+	if d.pos != len(d.data) {
+		return d.errorf("%d extra bytes", len(d.data)-d.pos)
+	}
+	s.root = root
+	return nil
+}
+
+func (n *node) appendBinary(b []byte) []byte {
+	b = append(b, byte(n.shift))
+	b = n.bitset.appendBinary(b)
+	for _, sn := range n.subnodes {
+		switch sub := sn.sub.(type) {
+		case *node:
+			b = sub.appendBinary(b)
+		case *set256:
+			b = sub.appendBinary(b)
+		}
+	}
+	return b
+}
+
+func (s *set256) appendBinary(b []byte) []byte {
+	var buf [32]byte
+	for i, t := range s.sets {
+		binary.LittleEndian.PutUint64(buf[8*i:], uint64(t))
+	}
+	return append(b, buf[:]...)
+}
+
+// A sparseDecoder decodes the nodes of a Sparse binary encoding.
+type sparseDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *sparseDecoder) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("bitset: invalid Sparse encoding at byte %d: %s", d.pos, fmt.Sprintf(format, args...))
+}
+
+// node decodes a node that should have the given shift.
+func (d *sparseDecoder) node(shift uint) (*node, error) {
+	if d.pos >= len(d.data) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if got := uint(d.data[d.pos]); got != shift {
+		return nil, d.errorf("node has shift %d, want %d", got, shift)
+	}
+	d.pos++
+	n := &node{shift: shift}
+	if err := d.set256(&n.bitset); err != nil {
+		return nil, err
+	}
+	n.subnodes = make([]subnode, 0, n.bitset.len())
+	var err error
+	n.bitset.indexes(func(index uint8) bool {
+		var sub subber
+		if shift == 8 {
+			leaf := &set256{}
+			err = d.set256(leaf)
+			sub = leaf
+		} else {
+			sub, err = d.node(shift - 8)
+		}
+		if err != nil {
+			return false
+		}
+		n.subnodes = append(n.subnodes, subnode{index: index, sub: sub})
+		n.count += sub.len()
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// set256 decodes a non-empty set256 into s.
+func (d *sparseDecoder) set256(s *set256) error {
+	if len(d.data)-d.pos < 32 {
+		return io.ErrUnexpectedEOF
+	}
+	for i := range s.sets {
+		s.sets[i] = Set64(binary.LittleEndian.Uint64(d.data[d.pos+8*i:]))
+	}
+	if s.empty() {
+		return d.errorf("empty set")
+	}
+	d.pos += 32
+	return nil
+}
+
+// The binary encoding of a Set64, version 1, is the version number followed by
+// the set as a little-endian uint64.
+const set64Version = 1
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (s Set64) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 9)
+	b[0] = set64Version
+	binary.LittleEndian.PutUint64(b[1:], uint64(s))
+	return b, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (s *Set64) UnmarshalBinary(data []byte) error {
+	if len(data) > 0 && data[0] != set64Version {
+		return fmt.Errorf("bitset: unknown Set64 encoding version %d", data[0])
+	}
+	if len(data) != 9 {
+		return fmt.Errorf("bitset: Set64 encoding has %d bytes, want 9", len(data))
+	}
+	*s = Set64(binary.LittleEndian.Uint64(data[1:]))
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder using the binary encoding.
+func (s Set64) GobEncode() ([]byte, error) { return s.MarshalBinary() }
+
+// GobDecode implements gob.GobDecoder using the binary encoding.
+func (s *Set64) GobDecode(data []byte) error { return s.UnmarshalBinary(data) }
+
+// GobEncode implements gob.GobEncoder using the binary encoding.
+func (s *Dense) GobEncode() ([]byte, error) { return s.MarshalBinary() }
+
+// GobDecode implements gob.GobDecoder using the binary encoding.
+func (s *Dense) GobDecode(data []byte) error { return s.UnmarshalBinary(data) }
+
+// GobEncode implements gob.GobEncoder using the binary encoding.
+func (s *Sparse) GobEncode() ([]byte, error) { return s.MarshalBinary() }
+
+// GobDecode implements gob.GobDecoder using the binary encoding.
+func (s *Sparse) GobDecode(data []byte) error { return s.UnmarshalBinary(data) }
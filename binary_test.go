@@ -0,0 +1,252 @@
+package bitset
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io"
+	"math"
+	"testing"
+)
+
+func TestDenseBinary(t *testing.T) {
+	for _, test := range []struct {
+		capacity int
+		els      []uint
+	}{
+		{0, nil},
+		{10, nil},
+		{100, []uint{0, 17, 63, 64, 99}},
+		{100000, []uint{1, 5000, 5001, 65535, 99999}},
+	} {
+		d := NewDense(test.capacity)
+		for _, e := range test.els {
+			d.Add(e)
+		}
+		data, err := d.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := len(data), denseHeaderSize+d.Cap()/8; got != want {
+			t.Errorf("%v: got %d bytes, want %d", test.els, got, want)
+		}
+		var got Dense
+		if err := got.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(d) || got.Cap() != d.Cap() {
+			t.Errorf("UnmarshalBinary: got %v, cap %d; want %v, cap %d", denseElts(&got), got.Cap(), test.els, d.Cap())
+		}
+
+		var buf bytes.Buffer
+		n, err := d.WriteTo(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != int64(len(data)) || !bytes.Equal(buf.Bytes(), data) {
+			t.Errorf("%v: WriteTo differs from MarshalBinary", test.els)
+		}
+		buf.WriteString("extra")
+		var got2 Dense
+		n, err = got2.ReadFrom(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != int64(len(data)) {
+			t.Errorf("ReadFrom: read %d bytes, want %d", n, len(data))
+		}
+		if !got2.Equal(d) || got2.Cap() != d.Cap() {
+			t.Errorf("ReadFrom: got %v, cap %d; want %v, cap %d", denseElts(&got2), got2.Cap(), test.els, d.Cap())
+		}
+		if buf.String() != "extra" {
+			t.Errorf("ReadFrom consumed too much: %q left", buf.String())
+		}
+	}
+}
+
+func TestDenseBinaryLayout(t *testing.T) {
+	d := NewDense(128)
+	d.Add(0)
+	d.Add(65)
+	data, _ := d.MarshalBinary()
+	want := []byte{
+		1, 0, 0, 0, 0, 0, 0, 0,
+		128, 0, 0, 0, 0, 0, 0, 0,
+		1, 0, 0, 0, 0, 0, 0, 0,
+		2, 0, 0, 0, 0, 0, 0, 0,
+	}
+	if !bytes.Equal(data, want) {
+		t.Errorf("got %v, want %v", data, want)
+	}
+}
+
+func TestDenseBinaryErrors(t *testing.T) {
+	good, _ := denseFrom([]uint{3, 70}).MarshalBinary()
+	for _, test := range []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"short header", good[:10]},
+		{"short words", good[:len(good)-1]},
+		{"long words", append(append([]byte(nil), good...), 0)},
+		{"version", modify(good, 0, 2)},
+		{"reserved", modify(good, 3, 1)},
+		{"capacity", modify(good, 8, 65)},
+		{"huge capacity", modify(good, 15, 0x80)},
+	} {
+		var d Dense
+		if err := d.UnmarshalBinary(test.data); err == nil {
+			t.Errorf("%s: UnmarshalBinary: got nil, want error", test.name)
+		}
+		if _, err := d.ReadFrom(bytes.NewReader(test.data)); err == nil && test.name != "long words" {
+			t.Errorf("%s: ReadFrom: got nil, want error", test.name)
+		}
+	}
+	var d Dense
+	if _, err := d.ReadFrom(bytes.NewReader(good[:20])); err != io.ErrUnexpectedEOF {
+		t.Errorf("got %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+// modify returns a copy of b with b[i] set to c.
+func modify(b []byte, i int, c byte) []byte {
+	b = append([]byte(nil), b...)
+	b[i] = c
+	return b
+}
+
+func TestSparseBinary(t *testing.T) {
+	for _, els := range [][]uint64{
+		nil,
+		{0},
+		{3, 17, 300, 12345, 1e8},
+		{1<<64 - 1, 1 << 63, 255, 256},
+		uRandSlice(1000),
+	} {
+		s := sparseFrom(els...)
+		data, err := s.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := sparseFrom(99) // UnmarshalBinary should replace existing contents.
+		if err := got.UnmarshalBinary(data); err != nil {
+			t.Fatalf("%v: %v", els, err)
+		}
+		if !got.Equal(s) {
+			t.Errorf("got %s, want %s", got, s)
+		}
+		if got.Len() != s.Len() {
+			t.Errorf("got len %d, want %d", got.Len(), s.Len())
+		}
+	}
+}
+
+func TestSparseBinaryLayout(t *testing.T) {
+	data, _ := sparseFrom(1, 2).MarshalBinary()
+	// Version, then seven nodes, each with a shift and a bitmap containing only 0,
+	// then a leaf with 1 and 2.
+	want := []byte{1}
+	for shift := 56; shift > 0; shift -= 8 {
+		want = append(want, byte(shift))
+		want = append(want, 1, 0, 0, 0, 0, 0, 0, 0)
+		want = append(want, make([]byte, 24)...)
+	}
+	want = append(want, 6, 0, 0, 0, 0, 0, 0, 0)
+	want = append(want, make([]byte, 24)...)
+	if !bytes.Equal(data, want) {
+		t.Errorf("got\n%v\nwant\n%v", data, want)
+	}
+}
+
+func TestSparseBinaryErrors(t *testing.T) {
+	good, _ := sparseFrom(1, 2, 1000).MarshalBinary()
+	const leaf = 1 + 7*33 // offset of the first leaf
+	for _, test := range []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"version", modify(good, 0, 2)},
+		{"truncated node", good[:20]},
+		{"truncated leaf", good[:len(good)-1]},
+		{"missing subnode", good[:len(good)-32]},
+		{"extra", append(append([]byte(nil), good...), 0)},
+		{"shift", modify(good, 1, 48)},
+		{"empty bitmap", modify(good, 2, 0)},
+		{"empty leaf", modify(good, leaf, 0)},
+		{"extra subnode", modify(good, 1+6*33+1, 7)},
+	} {
+		var s Sparse
+		if err := s.UnmarshalBinary(test.data); err == nil {
+			t.Errorf("%s: got nil, want error", test.name)
+		}
+	}
+}
+
+func TestSet64Binary(t *testing.T) {
+	for _, s := range []Set64{0, 1, sampleSet64(), Set64(math.MaxUint64)} {
+		data, err := s.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got Set64
+		if err := got.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		if got != s {
+			t.Errorf("got %s, want %s", got, s)
+		}
+	}
+	good, _ := sampleSet64().MarshalBinary()
+	for _, data := range [][]byte{nil, good[:8], modify(good, 0, 0), append(good, 0)} {
+		var s Set64
+		if err := s.UnmarshalBinary(data); err == nil {
+			t.Errorf("%v: got nil, want error", data)
+		}
+	}
+}
+
+func TestGob(t *testing.T) {
+	type value struct {
+		S64   Set64
+		D     *Dense
+		DV    Dense
+		P     *Sparse
+		PV    Sparse
+		Empty *Sparse
+	}
+	in := value{
+		S64:   sampleSet64(),
+		D:     denseFrom([]uint{1, 50, 99}),
+		DV:    *denseFrom([]uint{2, 3}),
+		P:     sparseFrom(uRandSlice(100)...),
+		PV:    *sparseFrom(7, 1<<60),
+		Empty: NewSparse(),
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&in); err != nil {
+		t.Fatal(err)
+	}
+	var out value
+	if err := gob.NewDecoder(&buf).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.S64 != in.S64 {
+		t.Errorf("Set64: got %s, want %s", out.S64, in.S64)
+	}
+	if !out.D.Equal(in.D) || out.D.Cap() != in.D.Cap() {
+		t.Errorf("*Dense: got %s, want %s", out.D, in.D)
+	}
+	if !out.DV.Equal(&in.DV) {
+		t.Errorf("Dense: got %s, want %s", &out.DV, &in.DV)
+	}
+	if !out.P.Equal(in.P) {
+		t.Errorf("*Sparse: got %s, want %s", out.P, in.P)
+	}
+	if !out.PV.Equal(&in.PV) {
+		t.Errorf("Sparse: got %s, want %s", &out.PV, &in.PV)
+	}
+	if out.Empty == nil || !out.Empty.Empty() {
+		t.Errorf("empty Sparse: got %v", out.Empty)
+	}
+}
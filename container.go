@@ -0,0 +1,306 @@
+package bitset
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// A container wraps one of the package's encodings of a set with enough
+// information to decode it without knowing its type in advance, and to detect
+// corruption. It is:
+//
+//	bytes 0-3:  the magic number "BSET"
+//	byte 4:     the container version, 1
+//	byte 5:     the type of the set: 1 for Set64, 2 for Dense, 3 for Sparse
+//	byte 6:     the encoding of the payload, a ContainerEncoding
+//	byte 7:     flags; bit 0 means the payload is compressed with compress/flate
+//	bytes 8-:   the payload
+//	last 4:     the CRC-32C of all the preceding bytes, little-endian
+const (
+	containerMagic      = "BSET"
+	containerVersion    = 1
+	containerHeaderSize = 8
+	containerFlagFlate  = 1
+)
+
+const (
+	containerSet64 = 1 + iota
+	containerDense
+	containerSparse
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// maxContainerPayload is the largest size, in bytes, to which DecodeContainer
+// will decompress a payload. It is a variable so tests can lower it.
+var maxContainerPayload int64 = 1 << 30
+
+// A ContainerEncoding is the encoding of the set inside a container.
+type ContainerEncoding uint8
+
+const (
+	// EncodingBinary is the MarshalBinary encoding. It is the only encoding for
+	// Set64, and the default for all types.
+	EncodingBinary ContainerEncoding = iota
+
+	// EncodingRLE is the run-length encoding of EncodeDenseRLE. It applies only
+	// to Dense.
+	EncodingRLE
+
+	// EncodingRoaring is the 32-bit Roaring format for Dense, and the 64-bit
+	// Roaring format for Sparse.
+	EncodingRoaring
+
+	// EncodingStream is the encoding of Encoder. It applies only to Sparse.
+	EncodingStream
+)
+
+// ContainerOptions controls how EncodeContainer encodes a set.
+type ContainerOptions struct {
+	Encoding ContainerEncoding
+	Compress bool // compress the payload with compress/flate
+}
+
+// EncodeContainer returns the container encoding of set, which must be a Set64,
+// *Set64, *Dense or *Sparse. A nil opts is the same as the zero value.
+func EncodeContainer(set interface{}, opts *ContainerOptions) ([]byte, error) {
+	if opts == nil {
+		opts = &ContainerOptions{}
+	}
+	var (
+		typ     byte
+		payload []byte
+		err     error
+	)
+	bad := func() error {
+		return fmt.Errorf("bitset: encoding %d does not apply to %T", opts.Encoding, set)
+	}
+	switch s := set.(type) {
+	case *Set64:
+		return EncodeContainer(*s, opts)
+	case Set64:
+		typ = containerSet64
+		if opts.Encoding != EncodingBinary {
+			return nil, bad()
+		}
+		payload, err = s.MarshalBinary()
+	case *Dense:
+		typ = containerDense
+		switch opts.Encoding {
+		case EncodingBinary:
+			payload, err = s.MarshalBinary()
+		case EncodingRLE:
+			payload = EncodeDenseRLE(s)
+		case EncodingRoaring:
+			var buf bytes.Buffer
+			err = WriteDenseRoaring(&buf, s)
+			payload = buf.Bytes()
+		default:
+			return nil, bad()
+		}
+	case *Sparse:
+		typ = containerSparse
+		switch opts.Encoding {
+		case EncodingBinary:
+			payload, err = s.MarshalBinary()
+		case EncodingRoaring:
+			var buf bytes.Buffer
+			err = WriteSparseRoaring64(&buf, s)
+			payload = buf.Bytes()
+		case EncodingStream:
+			var buf bytes.Buffer
+			err = NewEncoder(&buf).Encode(s)
+			payload = buf.Bytes()
+		default:
+			return nil, bad()
+		}
+	default:
+		return nil, fmt.Errorf("bitset: cannot encode %T in a container", set)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var flags byte
+	if opts.Compress {
+		flags |= containerFlagFlate
+		var buf bytes.Buffer
+		fw, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := fw.Write(payload); err != nil {
+			return nil, err
+		}
+		if err := fw.Close(); err != nil {
+			return nil, err
+		}
+		payload = buf.Bytes()
+	}
+	b := make([]byte, 0, containerHeaderSize+len(payload)+4)
+	b = append(b, containerMagic...)
+	b = append(b, containerVersion, typ, byte(opts.Encoding), flags)
+	b = append(b, payload...)
+	var sum [4]byte
+	binary.LittleEndian.PutUint32(sum[:], crc32.Checksum(b, castagnoli))
+	return append(b, sum[:]...), nil
+}
+
+// DecodeContainer decodes a container produced by EncodeContainer. It returns a
+// Set64, *Dense or *Sparse, according to the type recorded in the container.
+// Errors are of type *ContainerError. A compressed payload that decompresses to
+// more than 1 GiB is a ProblemCompression error.
+func DecodeContainer(data []byte) (interface{}, error) {
+	if len(data) < containerHeaderSize+4 {
+		return nil, &ContainerError{Problem: ProblemTruncated}
+	}
+	if string(data[:4]) != containerMagic {
+		return nil, &ContainerError{Problem: ProblemMagic}
+	}
+	if data[4] != containerVersion {
+		return nil, &ContainerError{Problem: ProblemVersion, Err: fmt.Errorf("version %d", data[4])}
+	}
+	body := data[:len(data)-4]
+	want := binary.LittleEndian.Uint32(data[len(data)-4:])
+	if got := crc32.Checksum(body, castagnoli); got != want {
+		return nil, &ContainerError{Problem: ProblemChecksum, Err: fmt.Errorf("got %#08x, want %#08x", got, want)}
+	}
+	typ, enc, flags := data[5], ContainerEncoding(data[6]), data[7]
+	if flags&^containerFlagFlate != 0 {
+		return nil, &ContainerError{Problem: ProblemFlags, Err: fmt.Errorf("flags %#x", flags)}
+	}
+	payload := body[containerHeaderSize:]
+	if flags&containerFlagFlate != 0 {
+		fr := flate.NewReader(bytes.NewReader(payload))
+		p, err := io.ReadAll(io.LimitReader(fr, maxContainerPayload+1))
+		if err != nil {
+			return nil, &ContainerError{Problem: ProblemCompression, Err: err}
+		}
+		if int64(len(p)) > maxContainerPayload {
+			return nil, &ContainerError{Problem: ProblemCompression, Err: fmt.Errorf("payload decompresses to more than %d bytes", maxContainerPayload)}
+		}
+		payload = p
+	}
+
+	var (
+		set interface{}
+		err error
+	)
+	badEncoding := &ContainerError{Problem: ProblemEncoding, Err: fmt.Errorf("encoding %d for type %d", enc, typ)}
+	switch typ {
+	case containerSet64:
+		if enc != EncodingBinary {
+			return nil, badEncoding
+		}
+		var s Set64
+		err = s.UnmarshalBinary(payload)
+		set = s
+	case containerDense:
+		switch enc {
+		case EncodingBinary:
+			s := NewDense(0)
+			err = s.UnmarshalBinary(payload)
+			set = s
+		case EncodingRLE:
+			set, err = DecodeDenseRLE(payload)
+		case EncodingRoaring:
+			r := bytes.NewReader(payload)
+			set, err = ReadDenseRoaring(r)
+			err = checkConsumed(err, r)
+		default:
+			return nil, badEncoding
+		}
+	case containerSparse:
+		switch enc {
+		case EncodingBinary:
+			s := NewSparse()
+			err = s.UnmarshalBinary(payload)
+			set = s
+		case EncodingRoaring:
+			r := bytes.NewReader(payload)
+			set, err = ReadSparseRoaring64(r)
+			err = checkConsumed(err, r)
+		case EncodingStream:
+			br := bufio.NewReader(bytes.NewReader(payload))
+			s := NewSparse()
+			err = checkConsumed(NewDecoder(br).Decode(s), br)
+			set = s
+		default:
+			return nil, badEncoding
+		}
+	default:
+		return nil, &ContainerError{Problem: ProblemType, Err: fmt.Errorf("type %d", typ)}
+	}
+	if err != nil {
+		return nil, &ContainerError{Problem: ProblemPayload, Err: err}
+	}
+	return set, nil
+}
+
+// checkConsumed returns err if it is non-nil, and otherwise an error if r has
+// more data.
+func checkConsumed(err error, r io.ByteReader) error {
+	if err != nil {
+		return err
+	}
+	if _, err := r.ReadByte(); err != io.EOF {
+		return errors.New("extra data after payload")
+	}
+	return nil
+}
+
+// A ContainerProblem classifies what is wrong with a container.
+type ContainerProblem int
+
+const (
+	ProblemTruncated   ContainerProblem = iota + 1 // too short to be a container
+	ProblemMagic                                   // wrong magic number
+	ProblemVersion                                 // unknown container version
+	ProblemChecksum                                // checksum mismatch
+	ProblemFlags                                   // unknown flags
+	ProblemCompression                             // payload cannot be decompressed
+	ProblemType                                    // unknown set type
+	ProblemEncoding                                // unknown encoding, or wrong one for the type
+	ProblemPayload                                 // payload cannot be decoded
+)
+
+var problemStrings = map[ContainerProblem]string{
+	ProblemTruncated:   "truncated",
+	ProblemMagic:       "bad magic number",
+	ProblemVersion:     "unknown version",
+	ProblemChecksum:    "checksum mismatch",
+	ProblemFlags:       "unknown flags",
+	ProblemCompression: "bad compressed data",
+	ProblemType:        "unknown set type",
+	ProblemEncoding:    "bad encoding",
+	ProblemPayload:     "bad payload",
+}
+
+func (p ContainerProblem) String() string {
+	if s, ok := problemStrings[p]; ok {
+		return s
+	}
+	return fmt.Sprintf("ContainerProblem(%d)", int(p))
+}
+
+// A ContainerError is returned by DecodeContainer when its input is invalid.
+type ContainerError struct {
+	Problem ContainerProblem
+	Err     error // more detail about the problem, or nil
+}
+
+func (e *ContainerError) Error() string {
+	if e.Err == nil {
+		return "bitset: container: " + e.Problem.String()
+	}
+	return fmt.Sprintf("bitset: container: %s: %v", e.Problem, e.Err)
+}
+
+// Unwrap returns e.Err.
+func (e *ContainerError) Unwrap() error { return e.Err }
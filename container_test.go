@@ -0,0 +1,124 @@
+package bitset
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"testing"
+)
+
+func TestContainerRoundTrip(t *testing.T) {
+	s64 := sampleSet64()
+	d := denseFrom(s{1, 2, 3, 50, 99})
+	sp := sparseFrom(append(uRandSlice(100), 1, 2, 3)...)
+	for _, test := range []struct {
+		set interface{}
+		enc ContainerEncoding
+	}{
+		{s64, EncodingBinary},
+		{&s64, EncodingBinary},
+		{d, EncodingBinary},
+		{d, EncodingRLE},
+		{d, EncodingRoaring},
+		{NewDense(0), EncodingBinary},
+		{sp, EncodingBinary},
+		{sp, EncodingRoaring},
+		{sp, EncodingStream},
+		{NewSparse(), EncodingStream},
+	} {
+		for _, compress := range []bool{false, true} {
+			data, err := EncodeContainer(test.set, &ContainerOptions{Encoding: test.enc, Compress: compress})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := DecodeContainer(data)
+			if err != nil {
+				t.Fatalf("%T, encoding %d, compress %t: %v", test.set, test.enc, compress, err)
+			}
+			ok := false
+			switch want := test.set.(type) {
+			case Set64:
+				ok = got == want
+			case *Set64:
+				ok = got == *want
+			case *Dense:
+				g, isDense := got.(*Dense)
+				ok = isDense && g.Equal(want)
+			case *Sparse:
+				g, isSparse := got.(*Sparse)
+				ok = isSparse && g.Equal(want)
+			}
+			if !ok {
+				t.Errorf("%T, encoding %d, compress %t: got %v", test.set, test.enc, compress, got)
+			}
+		}
+	}
+}
+
+func TestContainerErrors(t *testing.T) {
+	if _, err := EncodeContainer(sampleSet64(), &ContainerOptions{Encoding: EncodingRLE}); err == nil {
+		t.Error("Set64 with RLE: got nil, want error")
+	}
+	if _, err := EncodeContainer(NewSparse(), &ContainerOptions{Encoding: EncodingRLE}); err == nil {
+		t.Error("Sparse with RLE: got nil, want error")
+	}
+	if _, err := EncodeContainer("x", nil); err == nil {
+		t.Error("string: got nil, want error")
+	}
+
+	good, err := EncodeContainer(sparseFrom(1, 2, 3), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		name string
+		data []byte
+		want ContainerProblem
+	}{
+		{"empty", nil, ProblemTruncated},
+		{"magic", modify(good, 0, 'X'), ProblemMagic},
+		{"version", modify(good, 4, 9), ProblemVersion},
+		{"checksum", modify(good, 10, good[10]+1), ProblemChecksum},
+		{"flags", fixSum(modify(good, 7, 2)), ProblemFlags},
+		{"compression", fixSum(modify(good, 7, 1)), ProblemCompression},
+		{"type", fixSum(modify(good, 5, 9)), ProblemType},
+		{"encoding", fixSum(modify(good, 6, byte(EncodingRLE))), ProblemEncoding},
+		{"payload", fixSum(modify(good, 8, 9)), ProblemPayload},
+	} {
+		_, err := DecodeContainer(test.data)
+		var cerr *ContainerError
+		if !errors.As(err, &cerr) {
+			t.Errorf("%s: got %v, want a ContainerError", test.name, err)
+			continue
+		}
+		if cerr.Problem != test.want {
+			t.Errorf("%s: got %s, want %s", test.name, cerr.Problem, test.want)
+		}
+	}
+}
+
+func TestContainerDecompressionLimit(t *testing.T) {
+	// A large, empty Dense compresses very well.
+	c, err := EncodeContainer(NewDense(64*100000), &ContainerOptions{Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecodeContainer(c); err != nil {
+		t.Fatal(err)
+	}
+	defer func(m int64) { maxContainerPayload = m }(maxContainerPayload)
+	maxContainerPayload = 8 * 100000
+	_, err = DecodeContainer(c)
+	var cerr *ContainerError
+	if !errors.As(err, &cerr) || cerr.Problem != ProblemCompression {
+		t.Errorf("got %v, want ProblemCompression", err)
+	}
+}
+
+// fixSum replaces the last four bytes of b with the checksum of the rest, so
+// that DecodeContainer gets past the checksum test.
+func fixSum(b []byte) []byte {
+	n := len(b) - 4
+	binary.LittleEndian.PutUint32(b[n:], crc32.Checksum(b[:n], castagnoli))
+	return b
+}
@@ -0,0 +1,219 @@
+package bitset
+
+// A Cursor moves through the elements of a Sparse in increasing order, or in
+// decreasing order if it was created by ReverseCursor. Unlike Elements, it can
+// skip ahead: Seek moves past whole subtrees of the set's radix tree using their
+// presence bitmaps, rather than visiting their elements.
+//
+// The results of using a Cursor after its set has been modified are undefined.
+type Cursor struct {
+	root    *node
+	reverse bool
+	stack   [7]cursorFrame // one for each level of the tree, root first
+	value   uint64
+	started bool
+	valid   bool
+}
+
+// A cursorFrame records the position of a Cursor within one node.
+type cursorFrame struct {
+	n   *node
+	pos int // index into n.subnodes
+}
+
+// Cursor returns a Cursor for s, positioned before the first element.
+func (s *Sparse) Cursor() *Cursor {
+	return &Cursor{root: s.root}
+}
+
+// ReverseCursor returns a Cursor for s that moves from highest to lowest,
+// positioned before the highest element.
+func (s *Sparse) ReverseCursor() *Cursor {
+	return &Cursor{root: s.root, reverse: true}
+}
+
+// Value returns the element at which c is positioned. It should only be called
+// after Next or Seek has returned true.
+func (c *Cursor) Value() uint64 {
+	return c.value
+}
+
+// Next moves c to the next element, or to the first element if c has not yet
+// been moved. For a reverse Cursor, the next element is the next smaller one.
+// Next reports whether there is such an element.
+func (c *Cursor) Next() bool {
+	if !c.started {
+		return c.start(0, false)
+	}
+	if !c.valid {
+		return false
+	}
+	c.valid = c.advance()
+	return c.valid
+}
+
+// Seek moves c to the smallest element that is at least n, or for a reverse
+// Cursor, to the largest element that is at most n. If c is already positioned
+// at such an element, Seek does not move it. Seek reports whether there is such
+// an element.
+func (c *Cursor) Seek(n uint64) bool {
+	if !c.started {
+		return c.start(n, true)
+	}
+	if !c.valid {
+		return false
+	}
+	if c.value == n || (c.value > n) != c.reverse {
+		return true
+	}
+	// Find the first level at which n and the current value differ. The cursor
+	// is already in the right subtree at all the levels above it.
+	k := 0
+	for k < len(c.stack) && uint8(n>>c.stack[k].n.shift) == uint8(c.value>>c.stack[k].n.shift) {
+		k++
+	}
+	if k == len(c.stack) {
+		c.valid = c.nextInLeaf(uint8(n)) || c.pop(k-1)
+	} else {
+		c.valid = c.seekFrom(k, c.startPos(c.stack[k].n, n), n, true) || c.pop(k-1)
+	}
+	return c.valid
+}
+
+// In the comments below, "first", "later" and so on refer to the order in
+// which c moves: for a reverse Cursor, the first element is the largest.
+
+// start positions c at the first element, or the first one not before n if
+// bounded is true.
+func (c *Cursor) start(n uint64, bounded bool) bool {
+	c.started = true
+	if c.root == nil {
+		return false
+	}
+	c.stack[0].n = c.root
+	pos := c.firstPos(c.root)
+	if bounded {
+		pos = c.startPos(c.root, n)
+	}
+	c.valid = c.seekFrom(0, pos, n, bounded)
+	return c.valid
+}
+
+// firstPos returns the position of the first subnode of nd.
+func (c *Cursor) firstPos(nd *node) int {
+	if c.reverse {
+		return len(nd.subnodes) - 1
+	}
+	return 0
+}
+
+// startPos returns the position of the first subnode of nd that can hold
+// elements not before n.
+func (c *Cursor) startPos(nd *node, n uint64) int {
+	pos, found := nd.bitset.position(uint8(n >> nd.shift))
+	if c.reverse && !found {
+		// pos is the subnode after n's; the one before it is the first to
+		// hold smaller elements.
+		pos--
+	}
+	return pos
+}
+
+// advance moves c to the element after the current one.
+func (c *Cursor) advance() bool {
+	low := uint8(c.value)
+	if c.reverse {
+		if low > 0 && c.nextInLeaf(low-1) {
+			return true
+		}
+	} else if low < 255 && c.nextInLeaf(low+1) {
+		return true
+	}
+	return c.pop(len(c.stack) - 1)
+}
+
+// nextInLeaf moves c to the first element of the current leaf that is not before
+// lo, if there is one.
+func (c *Cursor) nextInLeaf(lo uint8) bool {
+	f := c.stack[len(c.stack)-1]
+	e, ok := c.leafNext(f.n.subnodes[f.pos].sub.(*set256), lo)
+	if ok {
+		c.value = c.value&^0xff | uint64(e)
+	}
+	return ok
+}
+
+func (c *Cursor) leafNext(s *set256, lo uint8) (uint8, bool) {
+	if c.reverse {
+		return s.prev(lo)
+	}
+	return s.next(lo)
+}
+
+// pop moves c to the first element after the subtrees it is positioned at on
+// levels k and below, looking at later subtrees on level k, then level k-1, and
+// so on up to the root.
+func (c *Cursor) pop(k int) bool {
+	step := 1
+	if c.reverse {
+		step = -1
+	}
+	for ; k >= 0; k-- {
+		if c.seekFrom(k, c.stack[k].pos+step, 0, false) {
+			return true
+		}
+	}
+	return false
+}
+
+// seekFrom moves c to the first element under the subnodes of c.stack[k].n at
+// position pos and later. If bounded is true, the element must also not be
+// before n, and n must belong in the subnode at pos or a later one.
+func (c *Cursor) seekFrom(k, pos int, n uint64, bounded bool) bool {
+	nd := c.stack[k].n
+	index := uint8(n >> nd.shift)
+	step := 1
+	if c.reverse {
+		step = -1
+	}
+	for ; pos >= 0 && pos < len(nd.subnodes); pos += step {
+		sn := nd.subnodes[pos]
+		c.stack[k].pos = pos
+		// Only the subnode that n belongs in limits the search; all the elements
+		// under later ones come after n.
+		b := bounded && sn.index == index
+		if nd.shift == 8 {
+			lo := uint8(0)
+			if c.reverse {
+				lo = 255
+			}
+			if b {
+				lo = uint8(n)
+			}
+			if e, ok := c.leafNext(sn.sub.(*set256), lo); ok {
+				c.value = c.prefix() | uint64(e)
+				return true
+			}
+			continue
+		}
+		child := sn.sub.(*node)
+		c.stack[k+1].n = child
+		start := c.firstPos(child)
+		if b {
+			start = c.startPos(child, n)
+		}
+		if c.seekFrom(k+1, start, n, b) {
+			return true
+		}
+	}
+	return false
+}
+
+// prefix returns the high 56 bits of the element at which c is positioned.
+func (c *Cursor) prefix() uint64 {
+	var v uint64
+	for _, f := range c.stack {
+		v |= uint64(f.n.subnodes[f.pos].index) << f.n.shift
+	}
+	return v
+}
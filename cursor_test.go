@@ -0,0 +1,128 @@
+package bitset
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// cursorTestElements returns elements that are spread out, along with some that
+// share leaves and subtrees.
+func cursorTestElements() []uint64 {
+	els := uRandSlice(200)
+	for i := 0; i < 300; i++ {
+		els = append(els, uint64(rand.Intn(1<<20)))
+	}
+	return append(els, 0, 255, 256, 1<<16-1, 1<<16, math.MaxUint64)
+}
+
+// cursorFor returns a Cursor for s going in the given direction.
+func cursorFor(s *Sparse, reverse bool) *Cursor {
+	if reverse {
+		return s.ReverseCursor()
+	}
+	return s.Cursor()
+}
+
+// before reports whether a comes before b in the given direction.
+func before(a, b uint64, reverse bool) bool {
+	if reverse {
+		return a > b
+	}
+	return a < b
+}
+
+func TestCursorNext(t *testing.T) {
+	for _, reverse := range []bool{false, true} {
+		for _, els := range [][]uint64{nil, {0}, {math.MaxUint64}, cursorTestElements()} {
+			c := cursorFor(sparseFrom(els...), reverse)
+			var got []uint64
+			for c.Next() {
+				got = append(got, c.Value())
+			}
+			want := uDedupSort(els)
+			if reverse {
+				slices.Reverse(want)
+			}
+			if !cmp.Equal(got, want) {
+				t.Errorf("reverse=%t: got %v, want %v", reverse, got, want)
+			}
+			if c.Next() || c.Seek(0) || c.Seek(math.MaxUint64) {
+				t.Errorf("reverse=%t: exhausted cursor moved", reverse)
+			}
+		}
+	}
+}
+
+func TestCursorSeek(t *testing.T) {
+	els := cursorTestElements()
+	s := sparseFrom(els...)
+	for _, reverse := range []bool{false, true} {
+		want := uDedupSort(els)
+		if reverse {
+			slices.Reverse(want)
+		}
+		// lowerBound returns the index of the first element of want that is not
+		// before n.
+		lowerBound := func(n uint64) int {
+			return sort.Search(len(want), func(i int) bool { return !before(want[i], n, reverse) })
+		}
+
+		// Seek from a fresh cursor.
+		for _, n := range append(uRandSlice(100), want...) {
+			for _, n := range []uint64{n - 1, n, n + 1} {
+				c := cursorFor(s, reverse)
+				i := lowerBound(n)
+				if got := c.Seek(n); got != (i < len(want)) {
+					t.Fatalf("reverse=%t: Seek(%d) = %t", reverse, n, got)
+				}
+				if i < len(want) && c.Value() != want[i] {
+					t.Errorf("reverse=%t: Seek(%d): got %d, want %d", reverse, n, c.Value(), want[i])
+				}
+			}
+		}
+
+		// Interleave seeks in the cursor's direction with calls to Next.
+		targets := append(uRandSlice(50), uint64(rand.Intn(1<<20)), uint64(rand.Intn(1<<20)), 256, 1<<16)
+		sort.Slice(targets, func(i, j int) bool { return before(targets[i], targets[j], reverse) })
+		c := cursorFor(s, reverse)
+		i := 0
+		for _, n := range targets {
+			if j := lowerBound(n); j > i {
+				i = j
+			}
+			if got := c.Seek(n); got != (i < len(want)) {
+				t.Fatalf("reverse=%t: Seek(%d) = %t", reverse, n, got)
+			}
+			if i == len(want) {
+				break
+			}
+			if c.Value() != want[i] {
+				t.Fatalf("reverse=%t: Seek(%d): got %d, want %d", reverse, n, c.Value(), want[i])
+			}
+			i++
+			if got := c.Next(); got != (i < len(want)) {
+				t.Fatalf("reverse=%t: Next after %d = %t", reverse, want[i-1], got)
+			}
+			if i < len(want) && c.Value() != want[i] {
+				t.Fatalf("reverse=%t: Next after %d: got %d, want %d", reverse, want[i-1], c.Value(), want[i])
+			}
+		}
+
+		// Seeking backwards does not move the cursor.
+		c = cursorFor(s, reverse)
+		c.Seek(1 << 20)
+		v := c.Value()
+		back := uint64(0)
+		if reverse {
+			back = math.MaxUint64
+		}
+		if !c.Seek(back) || c.Value() != v {
+			t.Errorf("reverse=%t: Seek(%d) moved the cursor from %d to %d", reverse, back, v, c.Value())
+		}
+	}
+}
@@ -1,9 +1,12 @@
 package bitset
 
+import "math/bits"
+
 // Dense is a standard bitset, represented as a sequence of bits. See Sparse in
 // this package for a more memory-efficient storage scheme for sparse bitsets.
 type Dense struct {
 	sets []Set64
+	rank []int // see BuildRankIndex; nil if there is no index
 }
 
 // NewDense creates a set capable of representing values in the range
@@ -25,6 +28,31 @@ func setslice(capacity int) []Set64 {
 	return make([]Set64, (capacity-1)/64+1)
 }
 
+// setsFromRuns returns a slice of Set64s just large enough to hold the
+// elements in the given [start, end] ranges, with those elements added.
+// Each end must be less than decodeLimit.
+func setsFromRuns(runs [][2]uint64) []Set64 {
+	max := uint64(0)
+	for _, r := range runs {
+		if r[1] > max {
+			max = r[1]
+		}
+	}
+	d := Dense{sets: setslice(int(max) + 1)}
+	for _, r := range runs {
+		d.addRange(uint(r[0]), uint(r[1]))
+	}
+	return d.sets
+}
+
+// addRange adds the elements of [start, end] to s, a word at a time.
+func (s *Dense) addRange(start, end uint) {
+	s.rank = nil
+	for i := start / 64; i <= end/64; i++ {
+		s.sets[i] |= wordMask(uint64(i), uint64(start), uint64(end)+1)
+	}
+}
+
 // Cap returns the maximum number of elements the set can contain,
 // which is one greater than the largest element it can contain.
 func (s *Dense) Cap() int {
@@ -33,6 +61,9 @@ func (s *Dense) Cap() int {
 
 // Len returns the number of elements in s.
 func (s *Dense) Len() int {
+	if s.rank != nil {
+		return s.rank[len(s.rank)-1]
+	}
 	sz := 0
 	for _, t := range s.sets {
 		sz += t.Len()
@@ -59,11 +90,13 @@ func (s *Dense) Copy() *Dense {
 
 // Add adds n to s.
 func (s *Dense) Add(n uint) {
+	s.rank = nil
 	s.sets[n/64].Add(uint8(n % 64))
 }
 
 // Remove removes n from s.
 func (s *Dense) Remove(n uint) {
+	s.rank = nil
 	s.sets[n/64].Remove(uint8(n % 64))
 }
 
@@ -74,6 +107,7 @@ func (s *Dense) Contains(n uint) bool {
 
 // Clear removes all elements from s.
 func (s *Dense) Clear() {
+	s.rank = nil
 	for i := range s.sets { // can't use _, t because it copies
 		s.sets[i].Clear()
 	}
@@ -84,6 +118,7 @@ func (s *Dense) SetCap(newCapacity int) {
 	newSets := setslice(newCapacity)
 	copy(newSets, s.sets)
 	s.sets = newSets
+	s.rank = nil
 }
 
 // Equal reports whether s2 has the same elements as s1. It may have a different capacity.
@@ -105,8 +140,49 @@ func (s1 *Dense) Equal(s2 *Dense) bool {
 	return true
 }
 
+// IsSubsetOf reports whether every element of s1 is in s2. The sets may have
+// different capacities.
+func (s1 *Dense) IsSubsetOf(s2 *Dense) bool {
+	min := minSetLen(s1, s2)
+	for i, t1 := range s1.sets[:min] {
+		if !t1.IsSubsetOf(s2.sets[i]) {
+			return false
+		}
+	}
+	for _, t1 := range s1.sets[min:] {
+		if t1 != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// IsSupersetOf reports whether every element of s2 is in s1. The sets may have
+// different capacities.
+func (s1 *Dense) IsSupersetOf(s2 *Dense) bool {
+	return s2.IsSubsetOf(s1)
+}
+
+// Intersects reports whether s1 and s2 have an element in common. The sets may
+// have different capacities.
+func (s1 *Dense) Intersects(s2 *Dense) bool {
+	for i, t1 := range s1.sets[:minSetLen(s1, s2)] {
+		if t1.Intersects(s2.sets[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDisjoint reports whether s1 and s2 have no elements in common. The sets may
+// have different capacities.
+func (s1 *Dense) IsDisjoint(s2 *Dense) bool {
+	return !s1.Intersects(s2)
+}
+
 // Complement replaces s with its complement.
 func (s *Dense) Complement() {
+	s.rank = nil
 	for i := 0; i < len(s.sets); i++ {
 		s.sets[i].Complement()
 	}
@@ -119,14 +195,33 @@ func (s1 *Dense) AddIn(s2 *Dense) {
 		// TODO: Grow s1 less if it's not necessary, or panic.
 		s1.SetCap(s2.Cap())
 	}
+	s1.rank = nil
 	for i, t2 := range s2.sets {
 		s1.sets[i].AddIn(t2)
 	}
 }
 
+// LenAddIn returns what s1.Len() would be after s1.AddIn(s2), without modifying
+// s1.
+func (s1 *Dense) LenAddIn(s2 *Dense) int {
+	min := minSetLen(s1, s2)
+	n := 0
+	for i, t := range s1.sets[:min] {
+		n += (t | s2.sets[i]).Len()
+	}
+	for _, t := range s1.sets[min:] {
+		n += t.Len()
+	}
+	for _, t := range s2.sets[min:] {
+		n += t.Len()
+	}
+	return n
+}
+
 // RemoveIn removes from s1 all the elements that are in s2.
 // It sets s1 to the set difference of s1 and s2.
 func (s1 *Dense) RemoveIn(s2 *Dense) {
+	s1.rank = nil
 	min := minSetLen(s1, s2)
 	for i := 0; i < min; i++ {
 		s1.sets[i].RemoveIn(s2.sets[i])
@@ -151,6 +246,7 @@ func (s1 *Dense) LenRemoveIn(s2 *Dense) int {
 // RemoveNotIn removes from s1 all the elements that are not in s2.
 // It sets s1 to the intersection of s1 and s2.
 func (s1 *Dense) RemoveNotIn(s2 *Dense) {
+	s1.rank = nil
 	min := minSetLen(s1, s2)
 	for i := 0; i < min; i++ {
 		s1.sets[i].RemoveNotIn(s2.sets[i])
@@ -161,6 +257,130 @@ func (s1 *Dense) RemoveNotIn(s2 *Dense) {
 
 }
 
+// LenRemoveNotIn returns what s1.Len() would be after s1.RemoveNotIn(s2),
+// without modifying s1.
+func (s1 *Dense) LenRemoveNotIn(s2 *Dense) int {
+	min := minSetLen(s1, s2)
+	n := 0
+	for i, t := range s1.sets[:min] {
+		n += (t & s2.sets[i]).Len()
+	}
+	return n
+}
+
+// XorIn removes from s1 the elements that are in s2, and adds those of s2 that
+// were not in s1. It sets s1 to the symmetric difference of s1 and s2.
+func (s1 *Dense) XorIn(s2 *Dense) {
+	if s1.Cap() < s2.Cap() {
+		s1.SetCap(s2.Cap())
+	}
+	s1.rank = nil
+	for i, t2 := range s2.sets {
+		s1.sets[i].XorIn(t2)
+	}
+}
+
+// LenXor returns what s1.Len() would be after s1.XorIn(s2), without modifying
+// s1.
+func (s1 *Dense) LenXor(s2 *Dense) int {
+	min := minSetLen(s1, s2)
+	n := 0
+	for i, t := range s1.sets[:min] {
+		n += (t ^ s2.sets[i]).Len()
+	}
+	for _, t := range s1.sets[min:] {
+		n += t.Len()
+	}
+	for _, t := range s2.sets[min:] {
+		n += t.Len()
+	}
+	return n
+}
+
+// NextSet returns the smallest element of s that is at least i. The second
+// return value is false if there is no such element.
+func (s *Dense) NextSet(i uint) (uint, bool) { return s.next(i, 0) }
+
+// NextClear returns the smallest value that is at least i, less than s.Cap(),
+// and not in s. The second return value is false if there is no such value.
+func (s *Dense) NextClear(i uint) (uint, bool) { return s.next(i, ^uint64(0)) }
+
+// PrevSet returns the largest element of s that is at most i. The second return
+// value is false if there is no such element.
+func (s *Dense) PrevSet(i uint) (uint, bool) { return s.prev(i, 0) }
+
+// PrevClear returns the largest value that is at most i, less than s.Cap(), and
+// not in s. The second return value is false if there is no such value.
+func (s *Dense) PrevClear(i uint) (uint, bool) { return s.prev(i, ^uint64(0)) }
+
+// Min returns the smallest element of s. The second return value is false if s
+// is empty.
+func (s *Dense) Min() (uint, bool) { return s.NextSet(0) }
+
+// Max returns the largest element of s. The second return value is false if s
+// is empty.
+func (s *Dense) Max() (uint, bool) { return s.PrevSet(^uint(0)) }
+
+// PopMin removes the smallest element of s and returns it. The second return
+// value is false if s is empty.
+func (s *Dense) PopMin() (uint, bool) {
+	n, ok := s.Min()
+	if ok {
+		s.Remove(n)
+	}
+	return n, ok
+}
+
+// PopMax removes the largest element of s and returns it. The second return
+// value is false if s is empty.
+func (s *Dense) PopMax() (uint, bool) {
+	n, ok := s.Max()
+	if ok {
+		s.Remove(n)
+	}
+	return n, ok
+}
+
+// next returns the position of the first 1 bit at or after i in the words of s,
+// each XOR'ed with flip.
+func (s *Dense) next(i uint, flip uint64) (uint, bool) {
+	w := i / 64
+	if w >= uint(len(s.sets)) {
+		return 0, false
+	}
+	if t := (uint64(s.sets[w]) ^ flip) >> (i % 64); t != 0 {
+		return i + uint(bits.TrailingZeros64(t)), true
+	}
+	for w++; w < uint(len(s.sets)); w++ {
+		if t := uint64(s.sets[w]) ^ flip; t != 0 {
+			return 64*w + uint(bits.TrailingZeros64(t)), true
+		}
+	}
+	return 0, false
+}
+
+// prev returns the position of the last 1 bit at or before i in the words of s,
+// each XOR'ed with flip.
+func (s *Dense) prev(i uint, flip uint64) (uint, bool) {
+	if len(s.sets) == 0 {
+		return 0, false
+	}
+	if max := uint(s.Cap()) - 1; i > max {
+		i = max
+	}
+	w := i / 64
+	if t := (uint64(s.sets[w]) ^ flip) << (63 - i%64); t != 0 {
+		return i - uint(bits.LeadingZeros64(t)), true
+	}
+	for w > 0 {
+		w--
+		if t := uint64(s.sets[w]) ^ flip; t != 0 {
+			return 64*w + 63 - uint(bits.LeadingZeros64(t)), true
+		}
+	}
+	return 0, false
+}
+
 func minSetLen(s1, s2 *Dense) int {
 	if len(s1.sets) <= len(s2.sets) {
 		return len(s1.sets)
@@ -184,3 +404,62 @@ func (s *Dense) Elements(f func([]uint) bool) {
 		}
 	}
 }
+
+// ElementsReverse calls f on successive slices of the set's elements, from
+// highest to lowest. If f returns false, the iteration stops. The slice passed
+// to f will be reused when f returns.
+func (s *Dense) ElementsReverse(f func([]uint) bool) {
+	var buf [64]uint
+	for i := len(s.sets) - 1; i >= 0; i-- {
+		n := s.sets[i].populateReverse(&buf)
+		offset := uint(64 * i)
+		for j := range buf[:n] {
+			buf[j] += offset
+		}
+		if !f(buf[:n]) {
+			break
+		}
+	}
+}
+
+// ElementsRange calls f on successive slices of the set's elements that are in
+// [lo, hi), from lowest to highest. If f returns false, the iteration stops.
+// The slice passed to f will be reused when f returns.
+func (s *Dense) ElementsRange(lo, hi uint, f func([]uint) bool) {
+	if c := uint(s.Cap()); hi > c {
+		hi = c
+	}
+	if lo >= hi {
+		return
+	}
+	var buf [64]uint
+	for i := lo / 64; i <= (hi-1)/64; i++ {
+		t := s.sets[i] & wordMask(uint64(i), uint64(lo), uint64(hi))
+		if t == 0 {
+			continue
+		}
+		n := t.populate(&buf)
+		offset := 64 * i
+		for j := range buf[:n] {
+			buf[j] += offset
+		}
+		if !f(buf[:n]) {
+			break
+		}
+	}
+}
+
+// elements64 is like Elements, but calls f on slices of uint64.
+func (s *Dense) elements64(f func([]uint64) bool) {
+	var buf [64]uint64
+	for i, t := range s.sets {
+		n := t.populate64(&buf)
+		offset := uint64(64 * i)
+		for j := range buf[:n] {
+			buf[j] += offset
+		}
+		if !f(buf[:n]) {
+			break
+		}
+	}
+}
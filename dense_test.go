@@ -167,6 +167,22 @@ func TestDenseBinaryFunctions(t *testing.T) {
 		if want := got.Len(); gotLen != want {
 			t.Errorf("%v LenRemoveIn %v: got %d, want %d", test.s1, test.s2, gotLen, want)
 		}
+		if got, want := d1.LenAddIn(d2), len(test.union); got != want {
+			t.Errorf("%v LenAddIn %v: got %d, want %d", test.s1, test.s2, got, want)
+		}
+		if got, want := d1.LenRemoveNotIn(d2), len(test.intersection); got != want {
+			t.Errorf("%v LenRemoveNotIn %v: got %d, want %d", test.s1, test.s2, got, want)
+		}
+
+		got = d1.Copy()
+		got.XorIn(d2)
+		wantXor := UnionDense(denseFrom(test.difference), DifferenceDense(d2, d1))
+		if !got.Equal(wantXor) {
+			t.Errorf("%v xor %v: got %v, want %v", test.s1, test.s2, got, wantXor)
+		}
+		if gotLen, want := d1.LenXor(d2), got.Len(); gotLen != want {
+			t.Errorf("%v LenXor %v: got %d, want %d", test.s1, test.s2, gotLen, want)
+		}
 		if want := denseFrom(test.s1); !d1.Equal(want) {
 			t.Errorf("%v does not equal %v", d1, want)
 		}
@@ -190,5 +206,176 @@ func TestDifferentCaps(t *testing.T) {
 	if want := 3; got != want {
 		t.Errorf("got %d, want %d", got, want)
 	}
+	for _, test := range []struct{ s1, s2 *Dense }{{d1, d2}, {d2, d1}} {
+		if got, want := test.s1.LenAddIn(test.s2), 7; got != want {
+			t.Errorf("LenAddIn: got %d, want %d", got, want)
+		}
+		if got, want := test.s1.LenRemoveNotIn(test.s2), 2; got != want {
+			t.Errorf("LenRemoveNotIn: got %d, want %d", got, want)
+		}
+		if got, want := test.s1.LenXor(test.s2), 5; got != want {
+			t.Errorf("LenXor: got %d, want %d", got, want)
+		}
+		x := test.s1.Copy()
+		x.XorIn(test.s2)
+		if got, want := x.String(), "{3, 5, 8, 11, 13}"; got != want {
+			t.Errorf("XorIn: got %s, want %s", got, want)
+		}
+	}
+
+}
 
+func TestDenseNextPrev(t *testing.T) {
+	// naive returns the first value v in [0, cap) reached from i by stepping
+	// with step for which s.Contains(v) == set.
+	naive := func(s *Dense, i int, step int, set bool) (uint, bool) {
+		if i >= s.Cap() && step < 0 {
+			i = s.Cap() - 1
+		}
+		for ; i >= 0 && i < s.Cap(); i += step {
+			if s.Contains(uint(i)) == set {
+				return uint(i), true
+			}
+		}
+		return 0, false
+	}
+	full := NewDense(192)
+	full.Complement()
+	for _, d := range []*Dense{
+		NewDense(0),
+		NewDense(100),
+		denseFrom(s{0, 5, 63, 64, 65, 99}),
+		denseFrom(s{127}),
+		full,
+	} {
+		for i := 0; i < d.Cap()+70; i++ {
+			u := uint(i)
+			check := func(name string, gotV uint, gotOK bool, wantV uint, wantOK bool) {
+				t.Helper()
+				if gotV != wantV || gotOK != wantOK {
+					t.Fatalf("%s: %s(%d) = (%d, %t), want (%d, %t)", d, name, i, gotV, gotOK, wantV, wantOK)
+				}
+			}
+			v, ok := d.NextSet(u)
+			wv, wok := naive(d, i, 1, true)
+			check("NextSet", v, ok, wv, wok)
+			v, ok = d.NextClear(u)
+			wv, wok = naive(d, i, 1, false)
+			check("NextClear", v, ok, wv, wok)
+			v, ok = d.PrevSet(u)
+			wv, wok = naive(d, i, -1, true)
+			check("PrevSet", v, ok, wv, wok)
+			v, ok = d.PrevClear(u)
+			wv, wok = naive(d, i, -1, false)
+			check("PrevClear", v, ok, wv, wok)
+		}
+	}
+}
+
+func TestDenseElementsRange(t *testing.T) {
+	d := denseFrom(s{0, 1, 5, 63, 64, 65, 70, 98, 99})
+	elts := denseElts(d)
+	for lo := uint(0); lo <= 110; lo++ {
+		for hi := uint(0); hi <= 110; hi++ {
+			var want []uint
+			for _, e := range elts {
+				if lo <= e && e < hi {
+					want = append(want, e)
+				}
+			}
+			var got []uint
+			d.ElementsRange(lo, hi, func(e []uint) bool {
+				got = append(got, e...)
+				return true
+			})
+			if !cmp.Equal(got, want) {
+				t.Fatalf("[%d, %d): got %v, want %v", lo, hi, got, want)
+			}
+		}
+	}
+	n := 0
+	d.ElementsRange(1, 100, func(e []uint) bool {
+		n++
+		return false
+	})
+	if n != 1 {
+		t.Errorf("f called %d times after returning false", n)
+	}
+}
+
+func TestDenseMinMax(t *testing.T) {
+	d := NewDense(200)
+	if _, ok := d.Min(); ok {
+		t.Error("Min of empty set succeeded")
+	}
+	if _, ok := d.PopMax(); ok {
+		t.Error("PopMax of empty set succeeded")
+	}
+	for _, e := range []uint{64, 3, 199, 70} {
+		d.Add(e)
+	}
+	if got, ok := d.Min(); !ok || got != 3 {
+		t.Errorf("Min = (%d, %t), want (3, true)", got, ok)
+	}
+	if got, ok := d.Max(); !ok || got != 199 {
+		t.Errorf("Max = (%d, %t), want (199, true)", got, ok)
+	}
+	var got []uint
+	for {
+		e, ok := d.PopMin()
+		if !ok {
+			break
+		}
+		got = append(got, e)
+		if e, ok := d.PopMax(); ok {
+			got = append(got, e)
+		}
+	}
+	if want := []uint{3, 199, 64, 70}; !cmp.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if !d.Empty() {
+		t.Errorf("%s is not empty", d)
+	}
+}
+
+func TestDensePredicates(t *testing.T) {
+	for _, test := range tests {
+		d1, d2 := denseFrom(test.s1), denseFrom(test.s2)
+		subset := len(test.difference) == 0
+		intersects := len(test.intersection) > 0
+		if got := d1.IsSubsetOf(d2); got != subset {
+			t.Errorf("%v IsSubsetOf %v = %t", test.s1, test.s2, got)
+		}
+		if got := d2.IsSupersetOf(d1); got != subset {
+			t.Errorf("%v IsSupersetOf %v = %t", test.s2, test.s1, got)
+		}
+		if got := d1.Intersects(d2); got != intersects {
+			t.Errorf("%v Intersects %v = %t", test.s1, test.s2, got)
+		}
+		if got := d2.IsDisjoint(d1); got == intersects {
+			t.Errorf("%v IsDisjoint %v = %t", test.s2, test.s1, got)
+		}
+	}
+
+	// Different capacities.
+	small := mustParseDense(t, "{1, 5}")
+	large := NewDense(1000)
+	large.Add(1)
+	large.Add(5)
+	if !small.IsSubsetOf(large) || !large.IsSubsetOf(small) {
+		t.Error("sets with the same elements are not subsets of each other")
+	}
+	large.Add(999)
+	if !small.IsSubsetOf(large) || large.IsSubsetOf(small) || small.IsSupersetOf(large) {
+		t.Errorf("wrong subset relation between %s and %s", small, large)
+	}
+	if !large.Intersects(small) || !small.Intersects(large) {
+		t.Errorf("%s and %s do not intersect", small, large)
+	}
+	large.Remove(1)
+	large.Remove(5)
+	if !large.IsDisjoint(small) || !small.IsDisjoint(large) {
+		t.Errorf("%s and %s are not disjoint", small, large)
+	}
 }
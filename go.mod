@@ -1,5 +1,5 @@
 module github.com/jba/bitset
 
-go 1.14
+go 1.23
 
 require github.com/google/go-cmp v0.4.0
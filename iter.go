@@ -0,0 +1,57 @@
+package bitset
+
+import "iter"
+
+// All returns an iterator over the elements of s, from lowest to highest.
+func (s Set64) All() iter.Seq[uint8] {
+	return func(yield func(uint8) bool) {
+		s.Elements(yieldAll(yield))
+	}
+}
+
+// Backward returns an iterator over the elements of s, from highest to lowest.
+func (s Set64) Backward() iter.Seq[uint8] {
+	return func(yield func(uint8) bool) {
+		s.ElementsReverse(yieldAll(yield))
+	}
+}
+
+// All returns an iterator over the elements of s, from lowest to highest.
+func (s *Dense) All() iter.Seq[uint] {
+	return func(yield func(uint) bool) {
+		s.Elements(yieldAll(yield))
+	}
+}
+
+// Backward returns an iterator over the elements of s, from highest to lowest.
+func (s *Dense) Backward() iter.Seq[uint] {
+	return func(yield func(uint) bool) {
+		s.ElementsReverse(yieldAll(yield))
+	}
+}
+
+// All returns an iterator over the elements of s, from lowest to highest.
+func (s *Sparse) All() iter.Seq[uint64] {
+	return func(yield func(uint64) bool) {
+		s.Elements(yieldAll(yield))
+	}
+}
+
+// Backward returns an iterator over the elements of s, from highest to lowest.
+func (s *Sparse) Backward() iter.Seq[uint64] {
+	return func(yield func(uint64) bool) {
+		s.ElementsReverse(yieldAll(yield))
+	}
+}
+
+// yieldAll adapts yield to the batched callbacks of Elements.
+func yieldAll[E any](yield func(E) bool) func([]E) bool {
+	return func(elts []E) bool {
+		for _, e := range elts {
+			if !yield(e) {
+				return false
+			}
+		}
+		return true
+	}
+}
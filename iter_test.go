@@ -0,0 +1,115 @@
+package bitset
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestIterators(t *testing.T) {
+	s64 := Set64From(0, 3, 17, 63)
+	if got, want := slices.Collect(s64.All()), []uint8{0, 3, 17, 63}; !cmp.Equal(got, want) {
+		t.Errorf("Set64.All: got %v, want %v", got, want)
+	}
+	if got, want := slices.Collect(s64.Backward()), []uint8{63, 17, 3, 0}; !cmp.Equal(got, want) {
+		t.Errorf("Set64.Backward: got %v, want %v", got, want)
+	}
+	if got := slices.Collect(Set64(0).All()); got != nil {
+		t.Errorf("empty Set64: got %v", got)
+	}
+
+	d := denseFrom(s{1, 2, 63, 64, 99})
+	if got, want := slices.Collect(d.All()), []uint{1, 2, 63, 64, 99}; !cmp.Equal(got, want) {
+		t.Errorf("Dense.All: got %v, want %v", got, want)
+	}
+	if got, want := slices.Collect(d.Backward()), []uint{99, 64, 63, 2, 1}; !cmp.Equal(got, want) {
+		t.Errorf("Dense.Backward: got %v, want %v", got, want)
+	}
+
+	els := uRandSlice(300)
+	sp := sparseFrom(els...)
+	want := uDedupSort(els)
+	if got := slices.Collect(sp.All()); !cmp.Equal(got, want) {
+		t.Errorf("Sparse.All: got %v, want %v", got, want)
+	}
+	slices.Reverse(want)
+	if got := slices.Collect(sp.Backward()); !cmp.Equal(got, want) {
+		t.Errorf("Sparse.Backward: got %v, want %v", got, want)
+	}
+	if got := slices.Collect(NewSparse().Backward()); got != nil {
+		t.Errorf("empty Sparse: got %v", got)
+	}
+}
+
+func TestIteratorBreak(t *testing.T) {
+	sp := sparseFrom(1, 2, 3, 1000, 1e9)
+	var got []uint64
+	for e := range sp.All() {
+		if e > 100 {
+			break
+		}
+		got = append(got, e)
+	}
+	if want := []uint64{1, 2, 3}; !cmp.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	got = nil
+	for e := range sp.Backward() {
+		if e < 100 {
+			break
+		}
+		got = append(got, e)
+	}
+	if want := []uint64{1e9, 1000}; !cmp.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	n := 0
+	for range denseFrom(s{1, 2, 3, 70, 80}).All() {
+		n++
+		if n == 4 {
+			break
+		}
+	}
+	for e := range Set64From(5, 6, 7).Backward() {
+		if e != 7 {
+			t.Errorf("got %d, want 7", e)
+		}
+		break
+	}
+}
+
+func TestElementsReverse(t *testing.T) {
+	var got8 []uint8
+	Set64From(0, 3, 17, 63).ElementsReverse(func(elts []uint8) bool {
+		got8 = append(got8, elts...)
+		return true
+	})
+	if want := []uint8{63, 17, 3, 0}; !cmp.Equal(got8, want) {
+		t.Errorf("Set64: got %v, want %v", got8, want)
+	}
+	Set64(0).ElementsReverse(func([]uint8) bool {
+		t.Error("empty Set64: f called")
+		return true
+	})
+
+	// Stop after the first slice.
+	var gotu []uint
+	denseFrom(s{1, 2, 63, 64, 99}).ElementsReverse(func(elts []uint) bool {
+		gotu = append(gotu, elts...)
+		return false
+	})
+	if want := []uint{99, 64}; !cmp.Equal(gotu, want) {
+		t.Errorf("Dense: got %v, want %v", gotu, want)
+	}
+
+	var got64 []uint64
+	sparseFrom(1, 2, 300, 1e9, 1e9+1).ElementsReverse(func(elts []uint64) bool {
+		got64 = append(got64, elts...)
+		return len(got64) < 3
+	})
+	if want := []uint64{1e9 + 1, 1e9, 300}; !cmp.Equal(got64, want) {
+		t.Errorf("Sparse: got %v, want %v", got64, want)
+	}
+}
@@ -0,0 +1,249 @@
+package bitset
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// A JSONFormat selects the JSON representation of a set.
+type JSONFormat int
+
+const (
+	// JSONArray represents a set as an array of its elements, in increasing
+	// order: [1,2,3,7].
+	JSONArray JSONFormat = iota
+
+	// JSONRanges represents a set as an array of [start,end] pairs, one for each
+	// maximal run of consecutive elements, in increasing order: [[1,3],[7,7]].
+	JSONRanges
+
+	// JSONBinary represents a set as a string holding the standard base64
+	// encoding of the set's MarshalBinary output.
+	JSONBinary
+)
+
+// JSONOptions controls how sets are encoded as JSON. The zero value encodes
+// sets as arrays of elements, as MarshalJSON does.
+type JSONOptions struct {
+	Format JSONFormat
+}
+
+var (
+	_ json.Marshaler   = Set64(0)
+	_ json.Unmarshaler = (*Set64)(nil)
+	_ json.Marshaler   = (*Dense)(nil)
+	_ json.Unmarshaler = (*Dense)(nil)
+	_ json.Marshaler   = (*Sparse)(nil)
+	_ json.Unmarshaler = (*Sparse)(nil)
+)
+
+// Marshal returns the JSON encoding of set, which must be a Set64, *Set64,
+// *Dense or *Sparse.
+func (o JSONOptions) Marshal(set interface{}) ([]byte, error) {
+	var (
+		es elementSet
+		bm encoding.BinaryMarshaler
+	)
+	switch s := set.(type) {
+	case Set64:
+		es, bm = s, s
+	case *Set64:
+		es, bm = *s, *s
+	case *Dense:
+		es, bm = s, s
+	case *Sparse:
+		es, bm = s, s
+	default:
+		return nil, fmt.Errorf("bitset: cannot marshal %T as JSON", set)
+	}
+
+	switch o.Format {
+	case JSONArray:
+		b := []byte{'['}
+		es.elements64(func(elts []uint64) bool {
+			for _, e := range elts {
+				if len(b) > 1 {
+					b = append(b, ',')
+				}
+				b = strconv.AppendUint(b, e, 10)
+			}
+			return true
+		})
+		return append(b, ']'), nil
+
+	case JSONRanges:
+		b := []byte{'['}
+		es.Runs(func(start, end uint64) bool {
+			if len(b) > 1 {
+				b = append(b, ',')
+			}
+			b = append(b, '[')
+			b = strconv.AppendUint(b, start, 10)
+			b = append(b, ',')
+			b = strconv.AppendUint(b, end, 10)
+			b = append(b, ']')
+			return true
+		})
+		return append(b, ']'), nil
+
+	case JSONBinary:
+		data, err := bm.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(data) // encoding/json uses base64 for []byte
+	default:
+		return nil, fmt.Errorf("bitset: unknown JSONFormat %d", o.Format)
+	}
+}
+
+// An elementSet is a set whose elements and runs can be enumerated as uint64s.
+type elementSet interface {
+	elements64(func([]uint64) bool)
+	Runs(func(start, end uint64) bool)
+}
+
+// MarshalJSON implements json.Marshaler. It encodes s as an array of its
+// elements. Use JSONOptions for other representations.
+func (s Set64) MarshalJSON() ([]byte, error) { return JSONOptions{}.Marshal(s) }
+
+// MarshalJSON implements json.Marshaler. It encodes s as an array of its
+// elements. Use JSONOptions for other representations.
+func (s *Dense) MarshalJSON() ([]byte, error) { return JSONOptions{}.Marshal(s) }
+
+// MarshalJSON implements json.Marshaler. It encodes s as an array of its
+// elements. Use JSONOptions for other representations.
+func (s *Sparse) MarshalJSON() ([]byte, error) { return JSONOptions{}.Marshal(s) }
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts any of the
+// representations described by JSONFormat; array items may be a mix of
+// elements and [start,end] pairs. It replaces the contents of s.
+func (s *Set64) UnmarshalJSON(data []byte) error {
+	var t Set64
+	isNull, err := unmarshalJSONSet(data, &t, func(start, end uint64) error {
+		if end >= 64 {
+			return fmt.Errorf("bitset: element %d out of range for Set64", end)
+		}
+		for e := start; e <= end; e++ {
+			t.Add(uint8(e))
+		}
+		return nil
+	})
+	if err == nil && !isNull {
+		*s = t
+	}
+	return err
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts any of the
+// representations described by JSONFormat; array items may be a mix of
+// elements and [start,end] pairs. It replaces the contents of s. When given
+// an array, it sets the capacity of s to the smallest that holds its largest
+// element; when given the binary representation, it uses the capacity
+// recorded there.
+func (s *Dense) UnmarshalJSON(data []byte) error {
+	var runs [][2]uint64
+	var t Dense
+	isNull, err := unmarshalJSONSet(data, &t, func(start, end uint64) error {
+		if end >= decodeLimit {
+			return fmt.Errorf("bitset: element %d out of range for Dense (limit %d)", end, decodeLimit)
+		}
+		runs = append(runs, [2]uint64{start, end})
+		return nil
+	})
+	if err != nil || isNull {
+		return err
+	}
+	if runs != nil {
+		t.sets = setsFromRuns(runs)
+	}
+	*s = t
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts any of the
+// representations described by JSONFormat; array items may be a mix of
+// elements and [start,end] pairs. It replaces the contents of s.
+func (s *Sparse) UnmarshalJSON(data []byte) error {
+	var t Sparse
+	tooMany := rangeLimiter()
+	isNull, err := unmarshalJSONSet(data, &t, func(start, end uint64) error {
+		if tooMany(start, end) {
+			return fmt.Errorf("bitset: ranges have more than %d elements", decodeLimit)
+		}
+		t.addRange(start, end)
+		return nil
+	})
+	if err == nil && !isNull {
+		*s = t
+	}
+	return err
+}
+
+// unmarshalJSONSet decodes the JSON representation of a set in data. If the
+// representation is binary, it unmarshals it into u. Otherwise it calls add with
+// each element or range of elements. It reports whether data is the JSON null,
+// which should leave the set unchanged.
+func unmarshalJSONSet(data []byte, u encoding.BinaryUnmarshaler, add func(start, end uint64) error) (isNull bool, err error) {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return true, nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return false, err
+		}
+		bin, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return false, fmt.Errorf("bitset: decoding JSON binary representation: %v", err)
+		}
+		return false, u.UnmarshalBinary(bin)
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return false, err
+	}
+	for _, item := range items {
+		var start, end uint64
+		if len(item) > 0 && item[0] == '[' {
+			var pair []json.RawMessage
+			if err := json.Unmarshal(item, &pair); err != nil {
+				return false, err
+			}
+			if len(pair) != 2 {
+				return false, fmt.Errorf("bitset: JSON range %s does not have two elements", item)
+			}
+			if start, err = parseJSONElement(pair[0]); err != nil {
+				return false, err
+			}
+			if end, err = parseJSONElement(pair[1]); err != nil {
+				return false, err
+			}
+			if start > end {
+				return false, fmt.Errorf("bitset: JSON range %s has start after end", item)
+			}
+		} else {
+			if start, err = parseJSONElement(item); err != nil {
+				return false, err
+			}
+			end = start
+		}
+		if err := add(start, end); err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+func parseJSONElement(raw json.RawMessage) (uint64, error) {
+	u, err := strconv.ParseUint(string(raw), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("bitset: invalid JSON element %s", raw)
+	}
+	return u, nil
+}
@@ -0,0 +1,174 @@
+package bitset
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	for _, test := range []struct {
+		format JSONFormat
+		els    []uint64
+		want   string
+	}{
+		{JSONArray, nil, `[]`},
+		{JSONArray, []uint64{1, 2, 3, 7}, `[1,2,3,7]`},
+		{JSONRanges, nil, `[]`},
+		{JSONRanges, []uint64{1, 2, 3, 7}, `[[1,3],[7,7]]`},
+		{JSONRanges, []uint64{0, 62, 63}, `[[0,0],[62,63]]`},
+		{JSONBinary, []uint64{0, 2}, `"AQUAAAAAAAAA"`},
+	} {
+		var s64 Set64
+		for _, e := range test.els {
+			s64.Add(uint8(e))
+		}
+		d := NewDense(64)
+		for _, e := range test.els {
+			d.Add(uint(e))
+		}
+		for _, set := range []interface{}{s64, d, sparseFrom(test.els...)} {
+			got, err := JSONOptions{Format: test.format}.Marshal(set)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := set.(Set64); !ok && test.format == JSONBinary {
+				continue // only the Set64 binary encoding is short enough to check
+			}
+			if string(got) != test.want {
+				t.Errorf("%T %v, format %d: got %s, want %s", set, test.els, test.format, got, test.want)
+			}
+		}
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	els := []uint64{0, 1, 2, 3, 17, 40, 41, 63}
+	for _, format := range []JSONFormat{JSONArray, JSONRanges, JSONBinary} {
+		opts := JSONOptions{Format: format}
+
+		s64 := Set64From(0, 1, 2, 3, 17, 40, 41, 63)
+		data, err := opts.Marshal(&s64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var gotSet64 Set64
+		if err := json.Unmarshal(data, &gotSet64); err != nil {
+			t.Fatal(err)
+		}
+		if gotSet64 != s64 {
+			t.Errorf("format %d: got %s, want %s", format, gotSet64, s64)
+		}
+
+		d := NewDense(1000)
+		for _, e := range els {
+			d.Add(uint(e) * 10)
+		}
+		data, err = opts.Marshal(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var gotDense Dense
+		if err := json.Unmarshal(data, &gotDense); err != nil {
+			t.Fatal(err)
+		}
+		if !gotDense.Equal(d) {
+			t.Errorf("format %d: got %v, want %v", format, denseElts(&gotDense), denseElts(d))
+		}
+
+		sp := sparseFrom(append(uRandSlice(50), els...)...)
+		data, err = opts.Marshal(sp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var gotSparse Sparse
+		if err := json.Unmarshal(data, &gotSparse); err != nil {
+			t.Fatal(err)
+		}
+		if !gotSparse.Equal(sp) {
+			t.Errorf("format %d: got %s, want %s", format, &gotSparse, sp)
+		}
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	var v struct {
+		S Set64
+		D *Dense
+		P *Sparse
+	}
+	data := `{"S": [1, [3, 5]], "D": [ [60,65], 2 ], "P": [[18446744073709551614, 18446744073709551615]]}`
+	if err := json.Unmarshal([]byte(data), &v); err != nil {
+		t.Fatal(err)
+	}
+	if want := Set64From(1, 3, 4, 5); v.S != want {
+		t.Errorf("got %s, want %s", v.S, want)
+	}
+	if want := denseFrom([]uint{2, 60, 61, 62, 63, 64, 65}); !v.D.Equal(want) || v.D.Cap() != 128 {
+		t.Errorf("got %v, cap %d; want %v, cap 128", denseElts(v.D), v.D.Cap(), denseElts(want))
+	}
+	if want := sparseFrom(1<<64-2, 1<<64-1); !v.P.Equal(want) {
+		t.Errorf("got %s, want %s", v.P, want)
+	}
+
+	// null leaves the value unchanged.
+	s := Set64From(9)
+	if err := json.Unmarshal([]byte("null"), &s); err != nil || s != Set64From(9) {
+		t.Errorf("got %s, %v; want {9}, nil", s, err)
+	}
+
+	for _, bad := range []string{`{}`, `[-1]`, `[1.5]`, `[[1]]`, `[[3,2]]`, `["x"]`, `[64]`, `"!!"`, `"AQ=="`} {
+		var s Set64
+		if err := json.Unmarshal([]byte(bad), &s); err == nil {
+			t.Errorf("%s: got nil, want error", bad)
+		}
+	}
+	if _, err := (JSONOptions{}).Marshal(3); err == nil {
+		t.Error("Marshal(3): got nil, want error")
+	}
+}
+
+func TestUnmarshalJSONLarge(t *testing.T) {
+	// Inputs that would demand too much memory or time are errors.
+	var d Dense
+	for _, in := range []string{"[9000000000000000000]", fmt.Sprintf("[%d]", decodeLimit), fmt.Sprintf("[[0,%d]]", decodeLimit)} {
+		if err := json.Unmarshal([]byte(in), &d); err == nil {
+			t.Errorf("Dense %s: got nil, want error", in)
+		}
+	}
+	var p Sparse
+	if err := json.Unmarshal([]byte("[[0,18446744073709551615]]"), &p); err == nil {
+		t.Error("Sparse: got nil, want error")
+	}
+
+	// Large ranges are filled a word at a time.
+	if err := json.Unmarshal([]byte("[[3,1000000],[999990,1000100]]"), &d); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := d.Len(), 1000100-3+1; got != want {
+		t.Errorf("Dense: got %d elements, want %d", got, want)
+	}
+	if d.Contains(2) || !d.Contains(3) || !d.Contains(1000100) {
+		t.Error("Dense: wrong elements at the ends of the range")
+	}
+	if err := json.Unmarshal([]byte("[[18446744073709551000,18446744073709551615],[5,300],[100,100]]"), &p); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := p.Len(), 616+296; got != want {
+		t.Errorf("Sparse: got %d elements, want %d", got, want)
+	}
+	checkCounts(t, &p)
+	var want Sparse
+	for e := uint64(5); e <= 300; e++ {
+		want.Add64(e)
+	}
+	for e := uint64(18446744073709551000); ; e++ {
+		want.Add64(e)
+		if e == 1<<64-1 {
+			break
+		}
+	}
+	if !p.Equal(&want) {
+		t.Errorf("Sparse: got %s, want %s", &p, &want)
+	}
+}
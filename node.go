@@ -9,6 +9,7 @@ type node struct {
 	shift    uint // how many bits to shift elements right
 	bitset   set256
 	subnodes []subnode // if shift > 0
+	count    int       // the number of elements in the subtree
 }
 
 type subnode struct {
@@ -19,17 +20,32 @@ type subnode struct {
 // subber is the interface satisifed by nodes of the tree.
 // It is implemented by node, for interior nodes, and set256, for leaves.
 type subber interface {
-	add64(uint64)
-	remove64(uint64) bool // returns true if empty
+	add64(uint64) bool    // returns true if the element was not present
+	remove64(uint64) bool // returns true if the element was present
 	contains64(uint64) bool
 	len() int
+	rank(uint64) int // the number of elements less than the argument
+	nth(k int) uint64
 	equal(subber) bool
+	isSubsetOf(subber) bool
+	intersects(subber) bool
 	copy() subber
 	addIn(subber)
 	removeIn(subber) bool    // returns true if empty
 	removeNotIn(subber) bool // returns true if empty
+	xorIn(subber) bool       // returns true if empty
+	lenIntersection(subber) int
 	memSize() uint64
 	elements(func([]uint64) bool, uint64) bool
+	elementsReverse(func([]uint64) bool, uint64) bool
+	elementsRange(f func([]uint64) bool, offset, lo, hi uint64) bool
+	runs(r *runBuilder, offset uint64) bool
+
+	// These return new subbers that share no memory with their arguments, or
+	// nil if the result is empty.
+	union(subber) subber
+	intersection(subber) subber
+	difference(subber) subber
 }
 
 func (n *node) newSubber() subber {
@@ -45,15 +61,16 @@ func (n *node) copyNode() *node {
 		return nil
 	}
 	n2 := *n
-	for i, sn := range n2.subnodes {
-		n2.subnodes[i].sub = sn.sub.copy()
+	n2.subnodes = make([]subnode, len(n.subnodes))
+	for i, sn := range n.subnodes {
+		n2.subnodes[i] = subnode{index: sn.index, sub: sn.sub.copy()}
 	}
 	return &n2
 }
 
 func (n *node) copy() subber { return n.copyNode() }
 
-func (n *node) add64(e uint64) {
+func (n *node) add64(e uint64) bool {
 	index := uint8(e >> n.shift)
 	pos, found := n.bitset.position(index)
 	var sub subber
@@ -63,25 +80,28 @@ func (n *node) add64(e uint64) {
 		sub = n.newSubber()
 		n.insertSubnode(pos, subnode{index: index, sub: sub})
 	}
-	sub.add64(e)
+	if !sub.add64(e) {
+		return false
+	}
+	n.count++
+	return true
 }
 
-func (n *node) remove64(e uint64) (empty bool) {
-	// n is not empty.
+func (n *node) remove64(e uint64) bool {
 	index := uint8(e >> n.shift)
 	pos, found := n.bitset.position(index)
 	if !found {
-		return false // We weren't empty coming in.
+		return false
 	}
 	sub := n.subnodes[pos].sub
-	if sub.remove64(e) {
-		if len(n.subnodes) == 1 {
-			// No need to clean up, we're finished.
-			return true
-		}
+	if !sub.remove64(e) {
+		return false
+	}
+	n.count--
+	if sub.len() == 0 {
 		n.deleteSubnode(pos)
 	}
-	return false
+	return true
 }
 
 func (n *node) insertSubnode(pos int, sn subnode) {
@@ -112,7 +132,7 @@ func (n *node) contains64(e uint64) bool {
 
 func (n1 *node) equal(sub subber) bool {
 	n2 := sub.(*node)
-	if !n1.bitset.equal(&n2.bitset) {
+	if n1.count != n2.count || !n1.bitset.equal(&n2.bitset) {
 		return false
 	}
 	for i, sn1 := range n1.subnodes {
@@ -123,12 +143,114 @@ func (n1 *node) equal(sub subber) bool {
 	return true
 }
 
-func (n *node) len() int {
-	t := 0
-	for _, s := range n.subnodes {
-		t += s.sub.len()
+func (n1 *node) isSubsetOf(sub subber) bool {
+	n2 := sub.(*node)
+	if n1.count > n2.count || !n1.bitset.isSubsetOf(&n2.bitset) {
+		return false
+	}
+	for _, sn1 := range n1.subnodes {
+		pos, _ := n2.bitset.position(sn1.index)
+		if !sn1.sub.isSubsetOf(n2.subnodes[pos].sub) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n1 *node) intersects(sub subber) bool {
+	n2 := sub.(*node)
+	if !n1.bitset.intersects(&n2.bitset) {
+		return false
+	}
+	i1 := 0
+	i2 := 0
+	for i1 < len(n1.subnodes) && i2 < len(n2.subnodes) {
+		sn1 := n1.subnodes[i1]
+		sn2 := n2.subnodes[i2]
+		switch {
+		case sn1.index < sn2.index:
+			i1++
+		case sn1.index > sn2.index:
+			i2++
+		default:
+			if sn1.sub.intersects(sn2.sub) {
+				return true
+			}
+			i1++
+			i2++
+		}
 	}
-	return t
+	return false
+}
+
+func (n *node) len() int { return n.count }
+
+// recount sets n.count from the counts of its subnodes.
+func (n *node) recount() {
+	n.count = 0
+	for _, sn := range n.subnodes {
+		n.count += sn.sub.len()
+	}
+}
+
+func (n *node) rank(e uint64) int {
+	pos, found := n.bitset.position(uint8(e >> n.shift))
+	// Add up the subnodes before pos, or subtract those after it from the
+	// total, whichever is fewer.
+	r := 0
+	if pos <= len(n.subnodes)/2 {
+		for _, sn := range n.subnodes[:pos] {
+			r += sn.sub.len()
+		}
+	} else {
+		r = n.count
+		for _, sn := range n.subnodes[pos:] {
+			r -= sn.sub.len()
+		}
+	}
+	if found {
+		r += n.subnodes[pos].sub.rank(e)
+	}
+	return r
+}
+
+// min returns the smallest element under n, which must not be empty.
+func (n *node) min() uint64 {
+	var e uint64
+	for {
+		sn := n.subnodes[0]
+		e |= uint64(sn.index) << n.shift
+		if n.shift == 8 {
+			low, _ := sn.sub.(*set256).next(0)
+			return e | uint64(low)
+		}
+		n = sn.sub.(*node)
+	}
+}
+
+// max returns the largest element under n, which must not be empty.
+func (n *node) max() uint64 {
+	var e uint64
+	for {
+		sn := n.subnodes[len(n.subnodes)-1]
+		e |= uint64(sn.index) << n.shift
+		if n.shift == 8 {
+			low, _ := sn.sub.(*set256).prev(255)
+			return e | uint64(low)
+		}
+		n = sn.sub.(*node)
+	}
+}
+
+func (n *node) nth(k int) uint64 {
+	for _, sn := range n.subnodes {
+		if l := sn.sub.len(); k >= l {
+			k -= l
+		} else {
+			return uint64(sn.index)<<n.shift | sn.sub.nth(k)
+		}
+	}
+	panic("bitset: node.nth: k out of range")
 }
 
 func (n *node) memSize() uint64 {
@@ -149,6 +271,52 @@ func (n *node) elements(f func([]uint64) bool, offset uint64) bool {
 	return true
 }
 
+func (n *node) elementsReverse(f func([]uint64) bool, offset uint64) bool {
+	for i := len(n.subnodes) - 1; i >= 0; i-- {
+		sn := n.subnodes[i]
+		if !sn.sub.elementsReverse(f, offset+uint64(sn.index)<<n.shift) {
+			return false
+		}
+	}
+	return true
+}
+
+// elementsRange is like elements, but only for the elements in [lo, hi), which
+// must overlap the range of elements that n can hold.
+func (n *node) elementsRange(f func([]uint64) bool, offset, lo, hi uint64) bool {
+	pos := 0
+	if lo > offset {
+		// Skip the subnodes below lo without looking at them.
+		pos, _ = n.bitset.position(uint8((lo - offset) >> n.shift))
+	}
+	for _, sn := range n.subnodes[pos:] {
+		start := offset + uint64(sn.index)<<n.shift
+		if start >= hi {
+			break
+		}
+		last := start + (1<<n.shift - 1)
+		var ok bool
+		if lo <= start && last < hi {
+			ok = sn.sub.elements(f, start)
+		} else {
+			ok = sn.sub.elementsRange(f, start, lo, hi)
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (n *node) runs(r *runBuilder, offset uint64) bool {
+	for _, sn := range n.subnodes {
+		if !sn.sub.runs(r, offset+uint64(sn.index)<<n.shift) {
+			return false
+		}
+	}
+	return true
+}
+
 func (n1 *node) addIn(s subber) {
 	n2 := s.(*node)
 	// Merge the lists of subnodes.
@@ -184,6 +352,7 @@ func (n1 *node) addIn(s subber) {
 		i1++
 		i2++
 	}
+	n1.recount()
 }
 
 func (n1 *node) removeIn(s subber) (empty bool) {
@@ -217,13 +386,106 @@ func (n1 *node) removeIn(s subber) (empty bool) {
 	if n1.bitset.empty() {
 		return true
 	}
-	if !removed {
-		return false
+	if removed {
+		n1.adjustSubnodes()
 	}
-	n1.adjustSubnodes()
+	n1.recount()
 	return false
 }
 
+func (n1 *node) union(s subber) subber {
+	n2 := s.(*node)
+	u := &node{shift: n1.shift, subnodes: make([]subnode, 0, len(n1.subnodes)+len(n2.subnodes))}
+	i1, i2 := 0, 0
+	for i1 < len(n1.subnodes) || i2 < len(n2.subnodes) {
+		var sn subnode
+		switch {
+		case i2 == len(n2.subnodes) || (i1 < len(n1.subnodes) && n1.subnodes[i1].index < n2.subnodes[i2].index):
+			sn = subnode{index: n1.subnodes[i1].index, sub: n1.subnodes[i1].sub.copy()}
+			i1++
+		case i1 == len(n1.subnodes) || n1.subnodes[i1].index > n2.subnodes[i2].index:
+			sn = subnode{index: n2.subnodes[i2].index, sub: n2.subnodes[i2].sub.copy()}
+			i2++
+		default:
+			sn = subnode{index: n1.subnodes[i1].index, sub: n1.subnodes[i1].sub.union(n2.subnodes[i2].sub)}
+			i1++
+			i2++
+		}
+		u.append(sn)
+	}
+	return u
+}
+
+func (n1 *node) intersection(s subber) subber {
+	n2 := s.(*node)
+	var r *node
+	i1, i2 := 0, 0
+	for i1 < len(n1.subnodes) && i2 < len(n2.subnodes) {
+		sn1 := n1.subnodes[i1]
+		sn2 := n2.subnodes[i2]
+		switch {
+		case sn1.index < sn2.index:
+			i1++
+		case sn1.index > sn2.index:
+			i2++
+		default:
+			if sub := sn1.sub.intersection(sn2.sub); sub != nil {
+				if r == nil {
+					r = &node{shift: n1.shift}
+				}
+				r.append(subnode{index: sn1.index, sub: sub})
+			}
+			i1++
+			i2++
+		}
+	}
+	if r == nil {
+		return nil
+	}
+	return r
+}
+
+func (n1 *node) difference(s subber) subber {
+	n2 := s.(*node)
+	var r *node
+	add := func(sn subnode) {
+		if r == nil {
+			r = &node{shift: n1.shift}
+		}
+		r.append(sn)
+	}
+	i1, i2 := 0, 0
+	for i1 < len(n1.subnodes) {
+		sn1 := n1.subnodes[i1]
+		switch {
+		case i2 == len(n2.subnodes) || sn1.index < n2.subnodes[i2].index:
+			// n2 has nothing to remove from this subnode.
+			add(subnode{index: sn1.index, sub: sn1.sub.copy()})
+			i1++
+		case sn1.index > n2.subnodes[i2].index:
+			i2++
+		default:
+			if sub := sn1.sub.difference(n2.subnodes[i2].sub); sub != nil {
+				add(subnode{index: sn1.index, sub: sub})
+			}
+			i1++
+			i2++
+		}
+	}
+	if r == nil {
+		return nil
+	}
+	return r
+}
+
+// append adds sn, which must be non-empty and have a larger index than the
+// existing subnodes of n, to the end of n's subnodes.
+func (n *node) append(sn subnode) {
+	n.subnodes = append(n.subnodes, sn)
+	n.bitset.add(sn.index)
+	n.count += sn.sub.len()
+}
+
 func (n *node) adjustSubnodes() {
 	// Change subnodes to match bitset.
 	sns := n.subnodes
@@ -273,9 +535,184 @@ func (n1 *node) removeNotIn(s subber) (empty bool) {
 	if n1.bitset.empty() {
 		return true
 	}
-	if !removed {
-		return false
+	if removed {
+		n1.adjustSubnodes()
 	}
-	n1.adjustSubnodes()
+	n1.recount()
 	return false
 }
+
+func (n1 *node) xorIn(s subber) (empty bool) {
+	n2 := s.(*node)
+	i1 := 0
+	i2 := 0
+	removed := false
+	for i1 < len(n1.subnodes) && i2 < len(n2.subnodes) {
+		sn1 := n1.subnodes[i1]
+		sn2 := n2.subnodes[i2]
+		switch {
+		case sn1.index < sn2.index:
+			// n1 has a chunk of elements that n2 does not. Keep it.
+			i1++
+
+		case sn1.index > sn2.index:
+			// n2 has elements that n1 does not. Add a copy of them.
+			n1.insertSubnode(i1, subnode{index: sn2.index, sub: sn2.sub.copy()})
+			i1++
+			i2++
+
+		default:
+			// sn1 and sn2 have the same index. If their elements cancel out,
+			// remove the subnode.
+			if sn1.sub.xorIn(sn2.sub) {
+				n1.bitset.remove(sn1.index)
+				removed = true
+			}
+			i1++
+			i2++
+		}
+	}
+	// If there are more n2 subnodes, copy them in.
+	for ; i2 < len(n2.subnodes); i2++ {
+		sn2 := n2.subnodes[i2]
+		n1.insertSubnode(len(n1.subnodes), subnode{index: sn2.index, sub: sn2.sub.copy()})
+	}
+	if n1.bitset.empty() {
+		return true
+	}
+	if removed {
+		n1.adjustSubnodes()
+	}
+	n1.recount()
+	return false
+}
+
+// lenIntersection returns the number of elements in both n1 and s, without
+// modifying either.
+func (n1 *node) lenIntersection(s subber) int {
+	n2 := s.(*node)
+	i1 := 0
+	i2 := 0
+	n := 0
+	for i1 < len(n1.subnodes) && i2 < len(n2.subnodes) {
+		sn1 := n1.subnodes[i1]
+		sn2 := n2.subnodes[i2]
+		switch {
+		case sn1.index < sn2.index:
+			i1++
+		case sn1.index > sn2.index:
+			i2++
+		default:
+			n += sn1.sub.lenIntersection(sn2.sub)
+			i1++
+			i2++
+		}
+	}
+	return n
+}
+
+// unionNodes returns a new node holding the union of ns, which must be
+// non-empty and all have the same shift. It merges all of ns at once, so each
+// subtree of the result is built only once.
+func unionNodes(ns []*node) *node {
+	u := &node{shift: ns[0].shift}
+	for _, n := range ns {
+		u.bitset.addIn(&n.bitset)
+	}
+	u.subnodes = make([]subnode, 0, u.bitset.len())
+	pos := make([]int, len(ns)) // the next subnode of each of ns
+	var kids []*node
+	u.bitset.indexes(func(index uint8) bool {
+		var sub subber
+		if u.shift == 8 {
+			leaf := &set256{}
+			for i, n := range ns {
+				if p := pos[i]; p < len(n.subnodes) && n.subnodes[p].index == index {
+					leaf.addIn(n.subnodes[p].sub)
+					pos[i]++
+				}
+			}
+			sub = leaf
+		} else {
+			kids = kids[:0]
+			for i, n := range ns {
+				if p := pos[i]; p < len(n.subnodes) && n.subnodes[p].index == index {
+					kids = append(kids, n.subnodes[p].sub.(*node))
+					pos[i]++
+				}
+			}
+			if len(kids) == 1 {
+				sub = kids[0].copyNode()
+			} else {
+				sub = unionNodes(kids)
+			}
+		}
+		u.subnodes = append(u.subnodes, subnode{index: index, sub: sub})
+		u.count += sub.len()
+		return true
+	})
+	return u
+}
+
+// intersectNodes returns a new node holding the intersection of ns, or nil if
+// it is empty. The nodes in ns must all have the same shift, and should be
+// ordered from smallest to largest, so that the search for common elements can
+// stop as soon as possible.
+func intersectNodes(ns []*node) *node {
+	common := ns[0].bitset
+	for _, n := range ns[1:] {
+		if common.removeNotIn(&n.bitset) {
+			return nil
+		}
+	}
+	var r *node
+	kids := make([]*node, len(ns))
+	common.indexes(func(index uint8) bool {
+		var sub subber
+		if ns[0].shift == 8 {
+			var leaf set256
+			for i, n := range ns {
+				pos, _ := n.bitset.position(index)
+				if i == 0 {
+					leaf = *n.subnodes[pos].sub.(*set256)
+				} else if leaf.removeNotIn(n.subnodes[pos].sub) {
+					return true
+				}
+			}
+			sub = &leaf
+		} else {
+			for i, n := range ns {
+				pos, _ := n.bitset.position(index)
+				kids[i] = n.subnodes[pos].sub.(*node)
+			}
+			k := intersectNodes(kids)
+			if k == nil {
+				return true
+			}
+			sub = k
+		}
+		if r == nil {
+			r = &node{shift: ns[0].shift}
+		}
+		r.append(subnode{index: index, sub: sub})
+		return true
+	})
+	return r
+}
+
+// descend returns the node with the given shift under n whose subtree holds e,
+// creating nodes along the way as needed. It adds delta to the counts of the
+// nodes along the way, including the one it returns.
+func (n *node) descend(e uint64, shift uint, delta int) *node {
+	n.count += delta
+	for n.shift > shift {
+		index := uint8(e >> n.shift)
+		pos, found := n.bitset.position(index)
+		if !found {
+			n.insertSubnode(pos, subnode{index: index, sub: n.newSubber()})
+		}
+		n = n.subnodes[pos].sub.(*node)
+		n.count += delta
+	}
+	return n
+}
@@ -0,0 +1,129 @@
+package bitset
+
+import "sort"
+
+// The functions in this file compute a new set from others, leaving them
+// unchanged. Go has no overloading, so the functions of two sets are named for
+// the type of set they build.
+
+// UnionDense returns a new set holding the elements that are in a, b or both.
+// Its capacity is the larger of the capacities of a and b.
+func UnionDense(a, b *Dense) *Dense {
+	if len(a.sets) < len(b.sets) {
+		a, b = b, a
+	}
+	sets := make([]Set64, len(a.sets))
+	for i, t := range b.sets {
+		sets[i] = a.sets[i] | t
+	}
+	copy(sets[len(b.sets):], a.sets[len(b.sets):])
+	return &Dense{sets: sets}
+}
+
+// IntersectionDense returns a new set holding the elements that are in both a
+// and b. Its capacity is the smaller of the capacities of a and b.
+func IntersectionDense(a, b *Dense) *Dense {
+	sets := make([]Set64, minSetLen(a, b))
+	for i := range sets {
+		sets[i] = a.sets[i] & b.sets[i]
+	}
+	return &Dense{sets: sets}
+}
+
+// DifferenceDense returns a new set holding the elements of a that are not in
+// b. Its capacity is that of a.
+func DifferenceDense(a, b *Dense) *Dense {
+	sets := make([]Set64, len(a.sets))
+	min := minSetLen(a, b)
+	for i := 0; i < min; i++ {
+		sets[i] = a.sets[i] &^ b.sets[i]
+	}
+	copy(sets[min:], a.sets[min:])
+	return &Dense{sets: sets}
+}
+
+// UnionSparse returns a new set holding the elements that are in a, b or both.
+func UnionSparse(a, b *Sparse) *Sparse {
+	switch {
+	case a.root == nil:
+		return b.Copy()
+	case b.root == nil:
+		return a.Copy()
+	}
+	return sparseFromSubber(a.root.union(b.root))
+}
+
+// IntersectionSparse returns a new set holding the elements that are in both a
+// and b.
+func IntersectionSparse(a, b *Sparse) *Sparse {
+	if a.root == nil || b.root == nil {
+		return NewSparse()
+	}
+	return sparseFromSubber(a.root.intersection(b.root))
+}
+
+// DifferenceSparse returns a new set holding the elements of a that are not in
+// b.
+func DifferenceSparse(a, b *Sparse) *Sparse {
+	switch {
+	case a.root == nil:
+		return NewSparse()
+	case b.root == nil:
+		return a.Copy()
+	}
+	return sparseFromSubber(a.root.difference(b.root))
+}
+
+// sparseFromSubber returns a Sparse whose root is sub, which is a root node or
+// nil.
+func sparseFromSubber(sub subber) *Sparse {
+	if sub == nil {
+		return NewSparse()
+	}
+	return &Sparse{root: sub.(*node)}
+}
+
+// UnionAll returns a new set holding the elements that are in any of sets. It
+// merges all the sets at once, which is faster than combining them one at a
+// time with AddIn.
+func UnionAll(sets ...*Sparse) *Sparse {
+	var roots []*node
+	for _, s := range sets {
+		if s.root != nil {
+			roots = append(roots, s.root)
+		}
+	}
+	switch len(roots) {
+	case 0:
+		return NewSparse()
+	case 1:
+		return &Sparse{root: roots[0].copyNode()}
+	}
+	return &Sparse{root: unionNodes(roots)}
+}
+
+// IntersectAll returns a new set holding the elements that are in all of sets.
+// If there are no sets, the result is empty. IntersectAll considers the sets
+// from smallest to largest, and stops looking at a subtree as soon as one set
+// has no elements there.
+func IntersectAll(sets ...*Sparse) *Sparse {
+	if len(sets) == 0 {
+		return NewSparse()
+	}
+	roots := make([]*node, len(sets))
+	for i, s := range sets {
+		if s.root == nil {
+			return NewSparse()
+		}
+		roots[i] = s.root
+	}
+	if len(roots) == 1 {
+		return &Sparse{root: roots[0].copyNode()}
+	}
+	sort.Slice(roots, func(i, j int) bool { return roots[i].count < roots[j].count })
+	r := intersectNodes(roots)
+	if r == nil {
+		return NewSparse()
+	}
+	return &Sparse{root: r}
+}
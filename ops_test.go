@@ -0,0 +1,178 @@
+package bitset
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestDenseOps(t *testing.T) {
+	for _, test := range tests {
+		d1, d2 := denseFrom(test.s1), denseFrom(test.s2)
+		for _, op := range []struct {
+			name string
+			f    func(a, b *Dense) *Dense
+			want []uint
+		}{
+			{"union", UnionDense, test.union},
+			{"intersection", IntersectionDense, test.intersection},
+			{"difference", DifferenceDense, test.difference},
+		} {
+			got := op.f(d1, d2)
+			if !got.Equal(denseFrom(op.want)) {
+				t.Errorf("%v %s %v: got %v, want %v", test.s1, op.name, test.s2, got, op.want)
+			}
+			got.Complement()
+			if !d1.Equal(denseFrom(test.s1)) || !d2.Equal(denseFrom(test.s2)) {
+				t.Fatalf("%v %s %v: inputs changed", test.s1, op.name, test.s2)
+			}
+		}
+	}
+
+	// Different capacities.
+	small, large := mustParseDense(t, "{1, 5, 60}"), mustParseDense(t, "{5, 60, 200}")
+	for _, test := range []struct {
+		got     *Dense
+		want    string
+		wantCap int
+	}{
+		{UnionDense(small, large), "{1, 5, 60, 200}", 256},
+		{UnionDense(large, small), "{1, 5, 60, 200}", 256},
+		{IntersectionDense(small, large), "{5, 60}", 64},
+		{IntersectionDense(large, small), "{5, 60}", 64},
+		{DifferenceDense(small, large), "{1}", 64},
+		{DifferenceDense(large, small), "{200}", 256},
+	} {
+		if got := test.got.String(); got != test.want || test.got.Cap() != test.wantCap {
+			t.Errorf("got %s with capacity %d, want %s with capacity %d", got, test.got.Cap(), test.want, test.wantCap)
+		}
+	}
+}
+
+func mustParseDense(t *testing.T, s string) *Dense {
+	t.Helper()
+	d, err := ParseDense(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestSparseOps(t *testing.T) {
+	clustered := func(n int) []uint64 {
+		els := uRandSlice(n / 10)
+		for i := 0; i < n; i++ {
+			els = append(els, uint64(rand.Intn(1<<14)))
+		}
+		return els
+	}
+	for _, test := range []struct {
+		in1, in2 []uint64
+	}{
+		{nil, nil},
+		{nil, []uint64{1}},
+		{[]uint64{1}, nil},
+		{[]uint64{17, 99}, []uint64{3, 500, 1000}},
+		{[]uint64{1, 2, 3}, []uint64{1, 2, 3}},
+		{clustered(300), clustered(300)},
+		{uRandSlice(100), uRandSlice(100)},
+	} {
+		s1, s2 := sparseFrom(test.in1...), sparseFrom(test.in2...)
+		for _, op := range []struct {
+			name string
+			f    func(a, b *Sparse) *Sparse
+			want []uint64
+		}{
+			{"union", UnionSparse, uUnion(test.in1, test.in2)},
+			{"intersection", IntersectionSparse, uIntersection(test.in1, test.in2)},
+			{"difference", DifferenceSparse, uDifference(test.in1, test.in2)},
+		} {
+			got := op.f(s1, s2)
+			checkCounts(t, got)
+			if want := sparseFrom(op.want...); !got.Equal(want) {
+				t.Fatalf("%s: got %s, want %s", op.name, got, want)
+			}
+			// The result must not share memory with the inputs.
+			for _, e := range uRandSlice(10) {
+				got.Add64(e)
+			}
+			got.AddIn(sparseFrom(test.in1...))
+			got.RemoveIn(sparseFrom(test.in2...))
+			got.Clear()
+			if !s1.Equal(sparseFrom(test.in1...)) || !s2.Equal(sparseFrom(test.in2...)) {
+				t.Fatalf("%s: inputs changed", op.name)
+			}
+		}
+	}
+}
+
+func TestSparseCopy(t *testing.T) {
+	els := append(uRandSlice(50), 1, 2, 3, 300)
+	s := sparseFrom(els...)
+	c := s.Copy()
+	if !c.Equal(s) {
+		t.Fatalf("got %s, want %s", c, s)
+	}
+	c.Remove64(2)
+	c.Remove64(300)
+	c.Add64(4)
+	for _, e := range els[:10] {
+		c.Remove64(e)
+	}
+	if want := sparseFrom(els...); !s.Equal(want) {
+		t.Errorf("original changed: got %s, want %s", s, want)
+	}
+	checkCounts(t, s)
+}
+
+func TestUnionIntersectAll(t *testing.T) {
+	clustered := func(n int) []uint64 {
+		var els []uint64
+		for i := 0; i < n; i++ {
+			els = append(els, uint64(rand.Intn(1<<12)))
+		}
+		return els
+	}
+	for _, inputs := range [][][]uint64{
+		nil,
+		{nil},
+		{{1, 2, 3}},
+		{{1, 2, 3}, nil},
+		{{1, 2, 3}, {2, 3, 4}, {3, 4, 5}},
+		{{1, 1 << 40}, {1 << 40, 1 << 50}, {1 << 40}},
+		{{5}, {6}},
+		{clustered(500), clustered(500), clustered(400), clustered(2000)},
+		{append(uRandSlice(50), 7, 300), uRandSlice(50), append(uRandSlice(50), 7, 300)},
+	} {
+		var sets []*Sparse
+		var union, inter []uint64
+		for i, in := range inputs {
+			sets = append(sets, sparseFrom(in...))
+			union = uUnion(union, in)
+			if i == 0 {
+				inter = uDedupSort(in)
+			} else {
+				inter = uIntersection(inter, in)
+			}
+		}
+
+		got := UnionAll(sets...)
+		checkCounts(t, got)
+		if want := sparseFrom(union...); !got.Equal(want) {
+			t.Errorf("UnionAll: got %s, want %s", got, want)
+		}
+		got.Clear()
+
+		got = IntersectAll(sets...)
+		checkCounts(t, got)
+		if want := sparseFrom(inter...); !got.Equal(want) {
+			t.Errorf("IntersectAll: got %s, want %s", got, want)
+		}
+		got.Add64(12345)
+
+		for i, in := range inputs {
+			if !sets[i].Equal(sparseFrom(in...)) {
+				t.Fatalf("input %d changed", i)
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package bitset
+
+import "sort"
+
+// rankBlock is the number of words of a Dense covered by each entry of its rank
+// index.
+const rankBlock = 8
+
+// BuildRankIndex builds an index that makes Rank take constant time and Select
+// take time logarithmic in the capacity of s. The index uses one int for every
+// 512 elements of capacity. Any change to s discards the index; call
+// BuildRankIndex again after a batch of changes to restore it.
+func (s *Dense) BuildRankIndex() {
+	rank := make([]int, (len(s.sets)+rankBlock-1)/rankBlock+1)
+	n := 0
+	for i, t := range s.sets {
+		if i%rankBlock == 0 {
+			rank[i/rankBlock] = n
+		}
+		n += t.Len()
+	}
+	rank[len(rank)-1] = n
+	s.rank = rank
+}
+
+// Rank returns the number of elements of s that are less than n.
+func (s *Dense) Rank(n uint) int {
+	if c := uint(s.Cap()); n > c {
+		n = c
+	}
+	w := int(n / 64)
+	r, i := 0, 0
+	if s.rank != nil {
+		r = s.rank[w/rankBlock]
+		i = w / rankBlock * rankBlock
+	}
+	for ; i < w; i++ {
+		r += s.sets[i].Len()
+	}
+	if w < len(s.sets) {
+		r += (s.sets[w] & wordMask(0, 0, uint64(n%64))).Len()
+	}
+	return r
+}
+
+// Select returns the k'th smallest element of s, counting from zero, so that
+// s.Rank(s.Select(k)) == k. The second return value is false if k is negative
+// or not less than s.Len().
+func (s *Dense) Select(k int) (uint, bool) {
+	if k < 0 {
+		return 0, false
+	}
+	i := 0
+	if s.rank != nil {
+		// Find the last block that starts with fewer than k+1 elements before it.
+		b := sort.Search(len(s.rank), func(b int) bool { return s.rank[b] > k }) - 1
+		if b == len(s.rank)-1 {
+			return 0, false
+		}
+		k -= s.rank[b]
+		i = b * rankBlock
+	}
+	for ; i < len(s.sets); i++ {
+		n := s.sets[i].Len()
+		if k < n {
+			e, _ := s.sets[i].Select(k)
+			return 64*uint(i) + uint(e), true
+		}
+		k -= n
+	}
+	return 0, false
+}
+
+// Rank returns the number of elements of s that are less than n.
+func (s *Sparse) Rank(n uint64) int {
+	if s.root == nil {
+		return 0
+	}
+	return s.root.rank(n)
+}
+
+// Select returns the k'th smallest element of s, counting from zero, so that
+// s.Rank(s.Select(k)) == k. The second return value is false if k is negative
+// or not less than s.Len().
+func (s *Sparse) Select(k int) (uint64, bool) {
+	if k < 0 || k >= s.Len() {
+		return 0, false
+	}
+	return s.root.nth(k), true
+}
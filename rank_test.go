@@ -0,0 +1,206 @@
+package bitset
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestDenseRankSelect(t *testing.T) {
+	big := NewDense(5000)
+	for i := 0; i < 1000; i++ {
+		big.Add(uint(rand.Intn(5000)))
+	}
+	full := NewDense(1024)
+	full.Complement()
+	for _, d := range []*Dense{
+		NewDense(0),
+		NewDense(100),
+		denseFrom(s{0, 5, 63, 64, 65, 99}),
+		full,
+		big,
+	} {
+		elts := denseElts(d)
+		check := func() {
+			t.Helper()
+			for n := 0; n <= d.Cap()+70; n++ {
+				want := 0
+				for _, e := range elts {
+					if e < uint(n) {
+						want++
+					}
+				}
+				if got := d.Rank(uint(n)); got != want {
+					t.Fatalf("%d elements: Rank(%d) = %d, want %d", len(elts), n, got, want)
+				}
+			}
+			for k := -1; k <= len(elts); k++ {
+				got, ok := d.Select(k)
+				if wantOK := k >= 0 && k < len(elts); ok != wantOK {
+					t.Fatalf("%d elements: Select(%d) returned %t", len(elts), k, ok)
+				}
+				if ok && got != elts[k] {
+					t.Fatalf("%d elements: Select(%d) = %d, want %d", len(elts), k, got, elts[k])
+				}
+			}
+			if got, want := d.Len(), len(elts); got != want {
+				t.Fatalf("Len = %d, want %d", got, want)
+			}
+		}
+		check()
+		d.BuildRankIndex()
+		check()
+	}
+}
+
+func TestDenseRankIndexInvalidation(t *testing.T) {
+	other := denseFrom(s{1, 2, 3, 90})
+	for _, mutate := range []func(*Dense){
+		func(d *Dense) { d.Add(700) },
+		func(d *Dense) { d.Remove(5) },
+		func(d *Dense) { d.Clear() },
+		func(d *Dense) { d.Complement() },
+		func(d *Dense) { d.SetCap(64) },
+		func(d *Dense) { d.AddIn(other) },
+		func(d *Dense) { d.RemoveIn(other) },
+		func(d *Dense) { d.RemoveNotIn(other) },
+		func(d *Dense) {
+			b, _ := other.MarshalBinary()
+			d.UnmarshalBinary(b)
+		},
+	} {
+		d := NewDense(1000)
+		for _, e := range []uint{1, 5, 90, 500, 999} {
+			d.Add(e)
+		}
+		d.BuildRankIndex()
+		mutate(d)
+		want := denseElts(d)
+		if got := d.Len(); got != len(want) {
+			t.Errorf("Len = %d, want %d", got, len(want))
+		}
+		if got := d.Rank(uint(d.Cap())); got != len(want) {
+			t.Errorf("Rank(Cap) = %d, want %d", got, len(want))
+		}
+		if len(want) > 0 {
+			if got, _ := d.Select(len(want) - 1); got != want[len(want)-1] {
+				t.Errorf("Select(last) = %d, want %d", got, want[len(want)-1])
+			}
+		}
+	}
+}
+
+func TestSparseRankSelect(t *testing.T) {
+	nums := append(uRandSlice(200), 0, 1, 255, 256, 1<<16, math.MaxUint64)
+	for i := 0; i < 300; i++ {
+		nums = append(nums, uint64(rand.Intn(1<<18)))
+	}
+	for _, els := range [][]uint64{nil, {0}, {math.MaxUint64}, nums} {
+		want := uDedupSort(els)
+		s := sparseFrom(els...)
+		if got := s.Len(); got != len(want) {
+			t.Fatalf("Len = %d, want %d", got, len(want))
+		}
+		for k, e := range want {
+			if got := s.Rank(e); got != k {
+				t.Fatalf("Rank(%d) = %d, want %d", e, got, k)
+			}
+			if e < math.MaxUint64 {
+				if got := s.Rank(e + 1); got != k+1 {
+					t.Fatalf("Rank(%d) = %d, want %d", e+1, got, k+1)
+				}
+			}
+			if got, ok := s.Select(k); !ok || got != e {
+				t.Fatalf("Select(%d) = (%d, %t), want (%d, true)", k, got, ok, e)
+			}
+		}
+		for _, k := range []int{-1, len(want)} {
+			if _, ok := s.Select(k); ok {
+				t.Errorf("Select(%d) succeeded", k)
+			}
+		}
+	}
+}
+
+// checkCounts fails if the count of any node of s differs from the number of
+// elements under it.
+func checkCounts(t *testing.T, s *Sparse) {
+	t.Helper()
+	var count func(n *node) int
+	count = func(n *node) int {
+		c := 0
+		for _, sn := range n.subnodes {
+			if sub, ok := sn.sub.(*node); ok {
+				c += count(sub)
+			} else {
+				c += sn.sub.len()
+			}
+		}
+		if c != n.count {
+			t.Fatalf("node with shift %d has count %d, want %d", n.shift, n.count, c)
+		}
+		return c
+	}
+	if s.root != nil {
+		count(s.root)
+	}
+}
+
+func TestSparseCounts(t *testing.T) {
+	small := func() []uint64 {
+		var els []uint64
+		for i := 0; i < 200; i++ {
+			els = append(els, uint64(rand.Intn(1<<12)))
+		}
+		return els
+	}
+	for i := 0; i < 10; i++ {
+		e1, e2 := append(small(), uRandSlice(20)...), small()
+		s1, s2 := sparseFrom(e1...), sparseFrom(e2...)
+		checkCounts(t, s1)
+		for _, e := range e2[:50] {
+			s1.Remove64(e)
+			s1.Remove64(e)
+		}
+		checkCounts(t, s1)
+
+		for _, test := range []struct {
+			op   func(s1, s2 *Sparse)
+			want func(u1, u2 []uint64) []uint64
+		}{
+			{(*Sparse).AddIn, uUnion},
+			{(*Sparse).RemoveIn, uDifference},
+			{(*Sparse).RemoveNotIn, uIntersection},
+		} {
+			c := sparseFrom(e1...)
+			test.op(c, s2)
+			checkCounts(t, c)
+			if got, want := c.Len(), len(uDedupSort(test.want(e1, e2))); got != want {
+				t.Fatalf("Len = %d, want %d", got, want)
+			}
+		}
+
+		var buf bytes.Buffer
+		if err := WriteSparseRoaring64(&buf, s1); err != nil {
+			t.Fatal(err)
+		}
+		r, err := ReadSparseRoaring64(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkCounts(t, r)
+		b, err := s1.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var u Sparse
+		if err := u.UnmarshalBinary(b); err != nil {
+			t.Fatal(err)
+		}
+		checkCounts(t, &u)
+		if r.Len() != s1.Len() || u.Len() != s1.Len() {
+			t.Fatalf("decoded Len = %d, %d, want %d", r.Len(), u.Len(), s1.Len())
+		}
+	}
+}
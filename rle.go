@@ -0,0 +1,199 @@
+package bitset
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// The run-length encoding of a Dense, version 1, compresses runs of Set64 words
+// that are all zeros or all ones, in the spirit of EWAH. It is:
+//
+//	byte 0:   the version number, 1
+//	uvarint:  the number of words, which is the capacity divided by 64
+//	markers:  a sequence of markers that together cover all the words
+//
+// Each marker is:
+//
+//	uvarint:  run<<1 | fill, meaning run words that are all fill bits
+//	uvarint:  the number of literal words that follow
+//	literals: that many little-endian uint64 words
+//
+// Words are covered in increasing order, so bit i of the k'th word covered
+// represents the element 64*k + i.
+const rleVersion = 1
+
+// EncodeDenseRLE returns the run-length encoding of s.
+func EncodeDenseRLE(s *Dense) []byte {
+	b := []byte{rleVersion}
+	b = appendUvarint(b, uint64(len(s.sets)))
+	for i := 0; i < len(s.sets); {
+		fill := s.sets[i] == Set64(^uint64(0))
+		start := i
+		for i < len(s.sets) && isFill(s.sets[i], fill) {
+			i++
+		}
+		run := i - start
+		start = i
+		for i < len(s.sets) && !isFill(s.sets[i], false) && !isFill(s.sets[i], true) {
+			i++
+		}
+		marker := uint64(run) << 1
+		if fill {
+			marker |= 1
+		}
+		b = appendUvarint(b, marker)
+		b = appendUvarint(b, uint64(i-start))
+		var buf [8]byte
+		for _, t := range s.sets[start:i] {
+			binary.LittleEndian.PutUint64(buf[:], uint64(t))
+			b = append(b, buf[:]...)
+		}
+	}
+	return b
+}
+
+// isFill reports whether t has all bits equal to fill.
+func isFill(t Set64, fill bool) bool {
+	if fill {
+		return t == Set64(^uint64(0))
+	}
+	return t == 0
+}
+
+// DecodeDenseRLE decodes the run-length encoding of a Dense in b. Since a few
+// bytes can describe an enormous set, it returns an error if the capacity of the
+// set would exceed 2^32, or the largest int on 32-bit platforms.
+func DecodeDenseRLE(b []byte) (*Dense, error) {
+	nwords, err := rleWalk(b, nil)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(nwords) > decodeLimit/64 {
+		return nil, fmt.Errorf("bitset: Dense run-length encoding has capacity %d, more than the limit of %d", 64*uint64(nwords), decodeLimit)
+	}
+	d := &Dense{sets: setslice(64 * nwords)}
+	i := 0
+	rleWalk(b, func(run int, fill bool, lits []byte) bool {
+		if fill {
+			for j := i; j < i+run; j++ {
+				d.sets[j] = Set64(^uint64(0))
+			}
+		}
+		i += run
+		for j := 0; j < len(lits); j += 8 {
+			d.sets[i] = Set64(binary.LittleEndian.Uint64(lits[j:]))
+			i++
+		}
+		return true
+	})
+	return d, nil
+}
+
+// DenseRLELen returns the number of elements in the set whose run-length
+// encoding is b, without decoding it.
+func DenseRLELen(b []byte) (int, error) {
+	n := 0
+	_, err := rleWalk(b, func(run int, fill bool, lits []byte) bool {
+		if fill {
+			n += 64 * run
+		}
+		for j := 0; j < len(lits); j += 8 {
+			n += bits.OnesCount64(binary.LittleEndian.Uint64(lits[j:]))
+		}
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// DenseRLEContains reports whether the set whose run-length encoding is b
+// contains n, without decoding it. If n is beyond the capacity of the set, the
+// result is false.
+func DenseRLEContains(b []byte, n uint) (bool, error) {
+	w := int(n / 64)
+	if uint(w) != n/64 { // overflow
+		w = maxInt
+	}
+	in := false
+	i := 0 // the index of the next word
+	_, err := rleWalk(b, func(run int, fill bool, lits []byte) bool {
+		if w < i+run {
+			in = fill
+			return false
+		}
+		i += run
+		if w < i+len(lits)/8 {
+			t := Set64(binary.LittleEndian.Uint64(lits[8*(w-i):]))
+			in = t.Contains(uint8(n % 64))
+			return false
+		}
+		i += len(lits) / 8
+		return true
+	})
+	if err != nil {
+		return false, err
+	}
+	return in, nil
+}
+
+// rleWalk checks the run-length encoding in b and returns its number of words.
+// If f is non-nil, rleWalk calls it on each marker until it returns false, after
+// which rleWalk stops checking.
+func rleWalk(b []byte, f func(run int, fill bool, lits []byte) bool) (int, error) {
+	if len(b) == 0 {
+		return 0, errors.New("bitset: empty Dense run-length encoding")
+	}
+	if b[0] != rleVersion {
+		return 0, fmt.Errorf("bitset: unknown Dense run-length encoding version %d", b[0])
+	}
+	pos := 1
+	uvarint := func() (uint64, error) {
+		u, n := binary.Uvarint(b[pos:])
+		if n <= 0 {
+			return 0, fmt.Errorf("bitset: invalid Dense run-length encoding at byte %d", pos)
+		}
+		pos += n
+		return u, nil
+	}
+	total, err := uvarint()
+	if err != nil {
+		return 0, err
+	}
+	if total > uint64(maxInt/64) {
+		return 0, fmt.Errorf("bitset: Dense run-length encoding has too many words (%d)", total)
+	}
+	nwords := int(total)
+	for covered := 0; covered < nwords; {
+		start := pos
+		marker, err := uvarint()
+		if err != nil {
+			return 0, err
+		}
+		nlits, err := uvarint()
+		if err != nil {
+			return 0, err
+		}
+		remaining := uint64(nwords - covered)
+		run := marker >> 1
+		if run > remaining || nlits > remaining-run {
+			return 0, fmt.Errorf("bitset: Dense run-length encoding marker at byte %d covers too many words", start)
+		}
+		if uint64(len(b)-pos)/8 < nlits {
+			return 0, fmt.Errorf("bitset: Dense run-length encoding truncated at byte %d", pos)
+		}
+		lits := b[pos : pos+8*int(nlits)]
+		pos += len(lits)
+		covered += int(run + nlits)
+		if f != nil && !f(int(run), marker&1 == 1, lits) {
+			return nwords, nil
+		}
+	}
+	if pos != len(b) {
+		return 0, fmt.Errorf("bitset: Dense run-length encoding has %d extra bytes", len(b)-pos)
+	}
+	return nwords, nil
+}
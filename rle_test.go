@@ -0,0 +1,121 @@
+package bitset
+
+import (
+	"bytes"
+	"errors"
+	"math/bits"
+	"math/rand"
+	"testing"
+)
+
+func TestDenseRLE(t *testing.T) {
+	runs := NewDense(64 * 100)
+	for i := uint(64 * 10); i < 64*60; i++ {
+		runs.Add(i)
+	}
+	runs.Add(3)
+	runs.Add(64*80 + 5)
+	random := NewDense(5000)
+	for i := 0; i < 500; i++ {
+		random.Add(uint(rand.Intn(5000)))
+	}
+	full := NewDense(640)
+	full.Complement()
+
+	for _, d := range []*Dense{NewDense(0), NewDense(100), denseFrom(s{1, 50, 99}), runs, random, full} {
+		enc := EncodeDenseRLE(d)
+		got, err := DecodeDenseRLE(enc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(d) || got.Cap() != d.Cap() {
+			t.Errorf("got %s, cap %d; want %s, cap %d", got, got.Cap(), d, d.Cap())
+		}
+		n, err := DenseRLELen(enc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != d.Len() {
+			t.Errorf("DenseRLELen: got %d, want %d", n, d.Len())
+		}
+		for i := uint(0); i < uint(d.Cap())+100; i++ {
+			got, err := DenseRLEContains(enc, i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := i < uint(d.Cap()) && d.Contains(i); got != want {
+				t.Fatalf("DenseRLEContains(%d): got %t, want %t", i, got, want)
+			}
+		}
+	}
+
+	// Runs compress well.
+	if got := len(EncodeDenseRLE(runs)); got > 40 {
+		t.Errorf("runs: encoding has %d bytes, want at most 40", got)
+	}
+	// version, 10 words, 10 fill words of ones
+	if got, want := EncodeDenseRLE(full), []byte{1, 10, 21, 0}; !bytes.Equal(got, want) {
+		t.Errorf("full: got %v, want %v", got, want)
+	}
+}
+
+func TestDenseRLEErrors(t *testing.T) {
+	good := EncodeDenseRLE(denseFrom(s{1, 50, 99}))
+	// good is: version, 2 words, no run, 2 literals, 16 bytes of literals.
+	for _, test := range []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"version", modify(good, 0, 2)},
+		{"truncated", good[:len(good)-1]},
+		{"extra", append(append([]byte(nil), good...), 0)},
+		{"too many words", modify(good, 3, 3)},
+		{"too few words", modify(good, 1, 3)},
+		{"bad varint", []byte{1, 0x80}},
+	} {
+		if _, err := DecodeDenseRLE(test.data); err == nil {
+			t.Errorf("%s: DecodeDenseRLE: got nil, want error", test.name)
+		}
+		if _, err := DenseRLELen(test.data); err == nil {
+			t.Errorf("%s: DenseRLELen: got nil, want error", test.name)
+		}
+	}
+}
+
+func TestDecodeDenseRLEHuge(t *testing.T) {
+	// Twenty bytes that describe 2^57-1 words of zeros.
+	const nwords = 1<<57 - 1
+	b := []byte{rleVersion}
+	b = appendUvarint(b, nwords)
+	b = appendUvarint(b, nwords<<1)
+	b = appendUvarint(b, 0)
+	if len(b) != 20 {
+		t.Fatalf("got %d bytes, want 20", len(b))
+	}
+	if _, err := DecodeDenseRLE(b); err == nil {
+		t.Error("got nil, want error")
+	}
+	// Counting doesn't allocate, so it can handle the encoding, unless the
+	// number of words doesn't fit in an int.
+	n, err := DenseRLELen(b)
+	if bits.UintSize == 32 {
+		if err == nil {
+			t.Errorf("DenseRLELen: got %d, nil; want error", n)
+		}
+	} else if n != 0 || err != nil {
+		t.Errorf("DenseRLELen: got %d, %v; want 0, nil", n, err)
+	}
+
+	// DecodeContainer reports the same problem.
+	c, err := EncodeContainer(NewDense(0), &ContainerOptions{Encoding: EncodingRLE})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c = append(append(c[:containerHeaderSize:containerHeaderSize], b...), 0, 0, 0, 0)
+	_, err = DecodeContainer(fixSum(c))
+	var cerr *ContainerError
+	if !errors.As(err, &cerr) || cerr.Problem != ProblemPayload {
+		t.Errorf("DecodeContainer: got %v, want ProblemPayload", err)
+	}
+}
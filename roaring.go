@@ -0,0 +1,436 @@
+package bitset
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+)
+
+// This file converts between sets and the Roaring bitmap portable
+// serialization format, described at
+// https://github.com/RoaringBitmap/RoaringFormatSpec. A Roaring bitmap divides
+// 32-bit values into containers of 65536 values each, keyed by their high 16
+// bits. That is exactly the range of a Sparse node with shift 8, and of 1024
+// consecutive Set64s in a Dense.
+//
+// The writers always produce array and bitmap containers, never run containers,
+// so their output matches that of CRoaring and RoaringBitmap for bitmaps that
+// have not been run-optimized. The readers accept all three container types.
+
+const (
+	roaringCookieNoRuns      = 12346
+	roaringCookie            = 12347
+	roaringNoOffsetThreshold = 4
+	roaringMaxArray          = 4096    // largest cardinality of an array container
+	roaringWords             = 1024    // uint64s in a bitmap container
+	roaringMaxRuns           = 1 << 15 // most separate runs in a container
+)
+
+// A roaringContainer describes one container of a 32-bit Roaring bitmap: the
+// elements whose high 16 bits are key.
+type roaringContainer struct {
+	key  uint16
+	card int
+	fill func(*[roaringWords]uint64) // writes the container's bitmap
+}
+
+// WriteSparseRoaring writes s to w in the 32-bit Roaring portable format.
+// It returns an error if s has an element that does not fit in 32 bits.
+func WriteSparseRoaring(w io.Writer, s *Sparse) error {
+	var cs []roaringContainer
+	for _, c := range s.chunks() {
+		if c.key > 0xffff {
+			return errors.New("bitset: Sparse has elements too large for a 32-bit Roaring bitmap")
+		}
+		cs = append(cs, c.container())
+	}
+	bw := bufio.NewWriter(w)
+	writeRoaring32(bw, cs)
+	return bw.Flush()
+}
+
+// WriteSparseRoaring64 writes s to w in the 64-bit Roaring portable format, as
+// read by CRoaring's roaring64_bitmap_portable_deserialize_safe and
+// RoaringBitmap's Roaring64NavigableMap.deserializePortable.
+func WriteSparseRoaring64(w io.Writer, s *Sparse) error {
+	chunks := s.chunks()
+	// Group the chunks by the high 32 bits of their elements.
+	var groups [][]sparseChunk
+	for i, c := range chunks {
+		if i == 0 || c.key>>16 != chunks[i-1].key>>16 {
+			groups = append(groups, nil)
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], c)
+	}
+	bw := bufio.NewWriter(w)
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], uint64(len(groups)))
+	bw.Write(b[:8])
+	for _, g := range groups {
+		binary.LittleEndian.PutUint32(b[:], uint32(g[0].key>>16))
+		bw.Write(b[:4])
+		cs := make([]roaringContainer, len(g))
+		for i, c := range g {
+			cs[i] = c.container()
+		}
+		writeRoaring32(bw, cs)
+	}
+	return bw.Flush()
+}
+
+// WriteDenseRoaring writes s to w in the 32-bit Roaring portable format.
+// It returns an error if s has an element that does not fit in 32 bits.
+func WriteDenseRoaring(w io.Writer, s *Dense) error {
+	var cs []roaringContainer
+	for start := 0; start < len(s.sets); start += roaringWords {
+		end := start + roaringWords
+		if end > len(s.sets) {
+			end = len(s.sets)
+		}
+		sets := s.sets[start:end]
+		card := 0
+		for _, t := range sets {
+			card += t.Len()
+		}
+		if card == 0 {
+			continue
+		}
+		if start/roaringWords > 0xffff {
+			return errors.New("bitset: Dense has elements too large for a 32-bit Roaring bitmap")
+		}
+		cs = append(cs, roaringContainer{
+			key:  uint16(start / roaringWords),
+			card: card,
+			fill: func(words *[roaringWords]uint64) {
+				for i, t := range sets {
+					words[i] = uint64(t)
+				}
+			},
+		})
+	}
+	bw := bufio.NewWriter(w)
+	writeRoaring32(bw, cs)
+	return bw.Flush()
+}
+
+// ReadSparseRoaring reads a bitmap in the 32-bit Roaring portable format from r
+// and returns it as a Sparse. It reads exactly the bytes of the bitmap from r.
+func ReadSparseRoaring(r io.Reader) (*Sparse, error) {
+	s := NewSparse()
+	err := readRoaring32(r, func(key uint16, words *[roaringWords]uint64) {
+		s.addChunk(uint64(key), words)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ReadSparseRoaring64 reads a bitmap in the 64-bit Roaring portable format from r
+// and returns it as a Sparse. It reads exactly the bytes of the bitmap from r.
+func ReadSparseRoaring64(r io.Reader) (*Sparse, error) {
+	var b [8]byte
+	if _, err := io.ReadFull(r, b[:8]); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	n := binary.LittleEndian.Uint64(b[:])
+	s := NewSparse()
+	var prev uint64
+	for i := uint64(0); i < n; i++ {
+		if _, err := io.ReadFull(r, b[:4]); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		high := uint64(binary.LittleEndian.Uint32(b[:]))
+		if i > 0 && high <= prev {
+			return nil, roaringErrorf("64-bit keys out of order")
+		}
+		prev = high
+		err := readRoaring32(r, func(key uint16, words *[roaringWords]uint64) {
+			s.addChunk(high<<16|uint64(key), words)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// ReadDenseRoaring reads a bitmap in the 32-bit Roaring portable format from r
+// and returns it as a Dense whose capacity is just large enough to hold its
+// largest element. It reads exactly the bytes of the bitmap from r.
+func ReadDenseRoaring(r io.Reader) (*Dense, error) {
+	d := NewDense(0)
+	err := readRoaring32(r, func(key uint16, words *[roaringWords]uint64) {
+		last := roaringWords - 1
+		for last >= 0 && words[last] == 0 {
+			last--
+		}
+		start := int(key) * roaringWords
+		if need := start + last + 1; len(d.sets) < need {
+			d.sets = append(d.sets, make([]Set64, need-len(d.sets))...)
+		}
+		for i, w := range words[:last+1] {
+			d.sets[start+i] = Set64(w)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// writeRoaring32 writes the containers to w as a 32-bit Roaring bitmap without
+// run containers. The caller must flush w and check its error.
+func writeRoaring32(w *bufio.Writer, cs []roaringContainer) {
+	var b [8]byte
+	put16 := func(u uint16) {
+		binary.LittleEndian.PutUint16(b[:], u)
+		w.Write(b[:2])
+	}
+	put32 := func(u uint32) {
+		binary.LittleEndian.PutUint32(b[:], u)
+		w.Write(b[:4])
+	}
+	put32(roaringCookieNoRuns)
+	put32(uint32(len(cs)))
+	for _, c := range cs {
+		put16(c.key)
+		put16(uint16(c.card - 1))
+	}
+	offset := 8 + 8*len(cs)
+	for _, c := range cs {
+		put32(uint32(offset))
+		if c.card <= roaringMaxArray {
+			offset += 2 * c.card
+		} else {
+			offset += 8 * roaringWords
+		}
+	}
+	var words [roaringWords]uint64
+	for _, c := range cs {
+		words = [roaringWords]uint64{}
+		c.fill(&words)
+		if c.card <= roaringMaxArray {
+			for i, x := range words {
+				for x != 0 {
+					put16(uint16(64*i + bits.TrailingZeros64(x)))
+					x &= x - 1
+				}
+			}
+		} else {
+			for _, x := range words {
+				binary.LittleEndian.PutUint64(b[:], x)
+				w.Write(b[:8])
+			}
+		}
+	}
+}
+
+// readRoaring32 reads a 32-bit Roaring bitmap from r, calling f with the key and
+// contents of each container in increasing key order. The words passed to f are
+// reused after f returns.
+func readRoaring32(r io.Reader, f func(key uint16, words *[roaringWords]uint64)) error {
+	read := func(b []byte) error {
+		_, err := io.ReadFull(r, b)
+		return unexpectedEOF(err)
+	}
+	var b [8]byte
+	if err := read(b[:4]); err != nil {
+		return err
+	}
+	cookie := binary.LittleEndian.Uint32(b[:])
+	var (
+		size       int
+		runFlags   []byte
+		hasOffsets = true
+	)
+	switch {
+	case cookie == roaringCookieNoRuns:
+		if err := read(b[:4]); err != nil {
+			return err
+		}
+		n := binary.LittleEndian.Uint32(b[:])
+		if n > 1<<16 {
+			return roaringErrorf("%d containers", n)
+		}
+		size = int(n)
+	case cookie&0xffff == roaringCookie:
+		size = int(cookie>>16) + 1
+		runFlags = make([]byte, (size+7)/8)
+		if err := read(runFlags); err != nil {
+			return err
+		}
+		hasOffsets = size >= roaringNoOffsetThreshold
+	default:
+		return roaringErrorf("bad cookie %#x", cookie)
+	}
+	header := make([]byte, 4*size)
+	if err := read(header); err != nil {
+		return err
+	}
+	if hasOffsets {
+		// We read the containers in order, so we don't need the offsets.
+		if err := read(make([]byte, 4*size)); err != nil {
+			return err
+		}
+	}
+	var (
+		words [roaringWords]uint64
+		buf   = make([]byte, 8*roaringWords)
+		runs  []byte // grown as run containers need it
+	)
+	for i := 0; i < size; i++ {
+		key := binary.LittleEndian.Uint16(header[4*i:])
+		card := int(binary.LittleEndian.Uint16(header[4*i+2:])) + 1
+		if i > 0 && key <= binary.LittleEndian.Uint16(header[4*(i-1):]) {
+			return roaringErrorf("keys out of order")
+		}
+		words = [roaringWords]uint64{}
+		n := 0 // cardinality actually read
+		switch {
+		case runFlags != nil && runFlags[i/8]&(1<<(i%8)) != 0:
+			if err := read(b[:2]); err != nil {
+				return err
+			}
+			nruns := int(binary.LittleEndian.Uint16(b[:]))
+			if nruns > roaringMaxRuns {
+				return roaringErrorf("%d runs", nruns)
+			}
+			if cap(runs) < 4*nruns {
+				runs = make([]byte, 4*nruns)
+			}
+			runs = runs[:4*nruns]
+			if err := read(runs); err != nil {
+				return err
+			}
+			next := 0 // smallest value the next run may start at
+			for j := 0; j < len(runs); j += 4 {
+				start := int(binary.LittleEndian.Uint16(runs[j:]))
+				end := start + int(binary.LittleEndian.Uint16(runs[j+2:]))
+				if start < next || end > 0xffff {
+					return roaringErrorf("bad run [%d, %d]", start, end)
+				}
+				setRange(words[:], start, end)
+				n += end - start + 1
+				next = end + 2
+			}
+
+		case card <= roaringMaxArray:
+			vals := buf[:2*card]
+			if err := read(vals); err != nil {
+				return err
+			}
+			for j := 0; j < len(vals); j += 2 {
+				v := binary.LittleEndian.Uint16(vals[j:])
+				if j > 0 && v <= binary.LittleEndian.Uint16(vals[j-2:]) {
+					return roaringErrorf("array container out of order")
+				}
+				words[v/64] |= 1 << (v % 64)
+			}
+			n = card
+
+		default:
+			if err := read(buf); err != nil {
+				return err
+			}
+			for j := range words {
+				words[j] = binary.LittleEndian.Uint64(buf[8*j:])
+				n += bits.OnesCount64(words[j])
+			}
+		}
+		if n != card {
+			return roaringErrorf("container %d has %d elements, header says %d", i, n, card)
+		}
+		f(key, &words)
+	}
+	return nil
+}
+
+func roaringErrorf(format string, args ...interface{}) error {
+	return fmt.Errorf("bitset: invalid Roaring bitmap: %s", fmt.Sprintf(format, args...))
+}
+
+// setRange adds the elements of [start, end] to the bitmap in words.
+func setRange(words []uint64, start, end int) {
+	for start <= end {
+		w := start / 64
+		lo := uint(start % 64)
+		hi := uint(63)
+		if end/64 == w {
+			hi = uint(end % 64)
+		}
+		words[w] |= (^uint64(0) >> (63 - hi)) &^ (1<<lo - 1)
+		start = 64*w + 64
+	}
+}
+
+// A sparseChunk is a node with shift 8, holding the elements of a Sparse whose
+// high 48 bits are key.
+type sparseChunk struct {
+	key uint64
+	n   *node
+}
+
+// chunks returns the chunks of s in increasing key order.
+func (s *Sparse) chunks() []sparseChunk {
+	var cs []sparseChunk
+	if s.root != nil {
+		s.root.chunks(0, func(key uint64, n *node) {
+			cs = append(cs, sparseChunk{key, n})
+		})
+	}
+	return cs
+}
+
+// chunks calls f on each node with shift 8 under n, along with the high bits of
+// the elements it holds. The high bits of the elements under n are key.
+func (n *node) chunks(key uint64, f func(uint64, *node)) {
+	if n.shift == 8 {
+		f(key, n)
+		return
+	}
+	for _, sn := range n.subnodes {
+		sn.sub.(*node).chunks(key<<8|uint64(sn.index), f)
+	}
+}
+
+func (c sparseChunk) container() roaringContainer {
+	return roaringContainer{
+		key:  uint16(c.key),
+		card: c.n.len(),
+		fill: func(words *[roaringWords]uint64) {
+			for _, sn := range c.n.subnodes {
+				for j, t := range sn.sub.(*set256).sets {
+					words[4*int(sn.index)+j] = uint64(t)
+				}
+			}
+		},
+	}
+}
+
+// addChunk adds to s the elements in words, which form the chunk with the
+// given key. The elements of s must all be smaller than those of the chunk.
+func (s *Sparse) addChunk(key uint64, words *[roaringWords]uint64) {
+	if s.root == nil {
+		s.init()
+	}
+	count := 0
+	for _, w := range words {
+		count += bits.OnesCount64(w)
+	}
+	n := s.root.descend(key<<16, 8, count)
+	for i := 0; i < roaringWords; i += 4 {
+		var leaf set256
+		for j := range leaf.sets {
+			leaf.sets[j] = Set64(words[i+j])
+		}
+		if leaf.empty() {
+			continue
+		}
+		n.subnodes = append(n.subnodes, subnode{index: uint8(i / 4), sub: &leaf})
+		n.bitset.add(uint8(i / 4))
+	}
+}
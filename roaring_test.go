@@ -0,0 +1,202 @@
+package bitset
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// roaring123 is the serialization of the 32-bit Roaring bitmap {1, 2, 3},
+// as produced by CRoaring.
+var roaring123 = []byte{
+	0x3a, 0x30, 0, 0, // cookie
+	1, 0, 0, 0, // one container
+	0, 0, 2, 0, // key 0, cardinality 3
+	16, 0, 0, 0, // offset of the container
+	1, 0, 2, 0, 3, 0, // the array container
+}
+
+func TestRoaringLayout(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteSparseRoaring(&buf, sparseFrom(3, 1, 2)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), roaring123) {
+		t.Errorf("Sparse: got %v, want %v", buf.Bytes(), roaring123)
+	}
+	buf.Reset()
+	if err := WriteDenseRoaring(&buf, denseFrom([]uint{1, 2, 3})); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), roaring123) {
+		t.Errorf("Dense: got %v, want %v", buf.Bytes(), roaring123)
+	}
+
+	// The 64-bit format prefixes each 32-bit bitmap with the high 32 bits.
+	buf.Reset()
+	if err := WriteSparseRoaring64(&buf, sparseFrom(1, 2, 3, 5<<32)); err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{2, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	want = append(want, roaring123...)
+	want = append(want, 5, 0, 0, 0)
+	want = append(want, 0x3a, 0x30, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 16, 0, 0, 0, 0, 0)
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("Sparse64: got %v, want %v", buf.Bytes(), want)
+	}
+}
+
+func TestReadRoaringRuns(t *testing.T) {
+	// A bitmap with one run container holding [10, 19] and 100, as written by
+	// CRoaring after roaring_bitmap_run_optimize.
+	data := []byte{
+		0x3b, 0x30, 0, 0, // cookie, one container
+		1,           // run flags
+		0, 0, 10, 0, // key 0, cardinality 11
+		2, 0, // two runs
+		10, 0, 9, 0,
+		100, 0, 0, 0,
+	}
+	s, err := ReadSparseRoaring(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := sparseFrom(10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 100)
+	if !s.Equal(want) {
+		t.Errorf("got %s, want %s", s, want)
+	}
+}
+
+func TestReadRoaringManyRuns(t *testing.T) {
+	// runContainer returns a bitmap with one run container of nruns runs, each
+	// holding the two values 3i and 3i+1.
+	runContainer := func(nruns int) []byte {
+		card := 2*nruns - 1
+		data := []byte{
+			0x3b, 0x30, 0, 0, // cookie, one container
+			1,                                 // run flags
+			0, 0, byte(card), byte(card >> 8), // key 0, cardinality
+			byte(nruns), byte(nruns >> 8),
+		}
+		for i := 0; i < nruns; i++ {
+			data = append(data, byte(3*i), byte(3*i>>8), 1, 0)
+		}
+		return data
+	}
+	const nruns = 3000 // more than fit in a bitmap container's bytes
+	s, err := ReadSparseRoaring(bytes.NewReader(runContainer(nruns)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.Len(), 2*nruns; got != want {
+		t.Errorf("got %d elements, want %d", got, want)
+	}
+	if max, _ := s.Max(); max != 3*(nruns-1)+1 {
+		t.Errorf("got max %d, want %d", max, 3*(nruns-1)+1)
+	}
+
+	// Too many runs to fit in 65536 values.
+	data := runContainer(1)
+	data[9], data[10] = 0xff, 0xff
+	if _, err := ReadSparseRoaring(bytes.NewReader(data)); err == nil {
+		t.Error("got nil error for 65535 runs")
+	}
+}
+
+func TestRoaringRoundTrip(t *testing.T) {
+	big := make([]uint64, 0, 5000)
+	for i := uint64(0); i < 5000; i++ {
+		big = append(big, 70000+3*i) // forces a bitmap container
+	}
+	for _, els := range [][]uint64{
+		nil,
+		{0},
+		{1<<24 - 1},
+		{1, 255, 256, 65535, 65536, 1e6, 1e7},
+		big,
+	} {
+		s := sparseFrom(els...)
+		var buf bytes.Buffer
+		if err := WriteSparseRoaring(&buf, s); err != nil {
+			t.Fatal(err)
+		}
+		data := buf.Bytes()
+		got, err := ReadSparseRoaring(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(s) {
+			t.Errorf("Sparse: got %s, want %s", got, s)
+		}
+
+		d, err := ReadDenseRoaring(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var us []uint
+		for _, e := range uDedupSort(els) {
+			us = append(us, uint(e))
+		}
+		if got := denseElts(d); !cmp.Equal(got, us) {
+			t.Errorf("Dense: got %v, want %v", got, us)
+		}
+		buf.Reset()
+		if err := WriteDenseRoaring(&buf, d); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), data) {
+			t.Errorf("%v: Dense and Sparse encodings differ", els)
+		}
+	}
+
+	// The largest 32-bit value needs a 512MB Dense, so only check Sparse.
+	s := sparseFrom(1<<32 - 1)
+	var buf bytes.Buffer
+	if err := WriteSparseRoaring(&buf, s); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ReadSparseRoaring(&buf); err != nil || !got.Equal(s) {
+		t.Errorf("got %v, %v; want %s", got, err, s)
+	}
+
+	for _, els := range [][]uint64{
+		nil,
+		{1 << 63, 1<<64 - 1},
+		append(uRandSlice(1000), big...),
+	} {
+		s := sparseFrom(els...)
+		var buf bytes.Buffer
+		if err := WriteSparseRoaring64(&buf, s); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ReadSparseRoaring64(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(s) {
+			t.Errorf("Sparse64: got %s, want %s", got, s)
+		}
+	}
+}
+
+func TestRoaringErrors(t *testing.T) {
+	if err := WriteSparseRoaring(&bytes.Buffer{}, sparseFrom(1<<32)); err == nil {
+		t.Error("WriteSparseRoaring: got nil, want error")
+	}
+	for _, test := range []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"cookie", modify(roaring123, 0, 0)},
+		{"truncated", roaring123[:len(roaring123)-1]},
+		{"cardinality", modify(roaring123, 10, 3)},
+		{"order", modify(roaring123, 18, 1)},
+		{"count", modify(roaring123, 6, 1)},
+	} {
+		if _, err := ReadSparseRoaring(bytes.NewReader(test.data)); err == nil {
+			t.Errorf("%s: got nil, want error", test.name)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package bitset
+
+import "math/bits"
+
+// Runs calls f with the first and last elements of each maximal run of
+// consecutive elements of s, in increasing order. If f returns false, the
+// iteration stops.
+func (s Set64) Runs(f func(start, end uint64) bool) {
+	r := runBuilder{f: f}
+	if r.word(0, uint64(s)) {
+		r.flush()
+	}
+}
+
+// Runs calls f with the first and last elements of each maximal run of
+// consecutive elements of s, in increasing order. If f returns false, the
+// iteration stops.
+func (s *Dense) Runs(f func(start, end uint64) bool) {
+	r := runBuilder{f: f}
+	for i, t := range s.sets {
+		if !r.word(uint64(64*i), uint64(t)) {
+			return
+		}
+	}
+	r.flush()
+}
+
+// Runs calls f with the first and last elements of each maximal run of
+// consecutive elements of s, in increasing order. If f returns false, the
+// iteration stops.
+func (s *Sparse) Runs(f func(start, end uint64) bool) {
+	if s.root == nil {
+		return
+	}
+	r := runBuilder{f: f}
+	if s.root.runs(&r, 0) {
+		r.flush()
+	}
+}
+
+// A runBuilder finds the maximal runs in a sequence of words that are presented
+// in increasing order, possibly with gaps between them, and passes them to f.
+type runBuilder struct {
+	f          func(start, end uint64) bool
+	start, end uint64 // the current run, if inRun
+	inRun      bool
+}
+
+// word adds the elements in w, whose bit 0 represents base. It returns false if
+// f does.
+func (r *runBuilder) word(base, w uint64) bool {
+	for w != 0 {
+		// Find the next run of 1 bits in w with two bit counts, so a full word
+		// takes one step.
+		lo := bits.TrailingZeros64(w)
+		n := bits.TrailingZeros64(^(w >> lo))
+		start := base + uint64(lo)
+		end := start + uint64(n-1)
+		if r.inRun && start == r.end+1 {
+			r.end = end
+		} else {
+			if r.inRun && !r.f(r.start, r.end) {
+				return false
+			}
+			r.start, r.end, r.inRun = start, end, true
+		}
+		w &^= 1<<(lo+n) - 1 // when lo+n is 64, this clears all of w
+	}
+	return true
+}
+
+// flush passes the last run, if any, to f.
+func (r *runBuilder) flush() {
+	if r.inRun {
+		r.f(r.start, r.end)
+	}
+}
@@ -0,0 +1,104 @@
+package bitset
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// naiveRuns returns the maximal runs of the sorted, distinct elements els.
+func naiveRuns(els []uint64) [][2]uint64 {
+	var runs [][2]uint64
+	for _, e := range els {
+		if n := len(runs); n > 0 && runs[n-1][1]+1 == e {
+			runs[n-1][1] = e
+		} else {
+			runs = append(runs, [2]uint64{e, e})
+		}
+	}
+	return runs
+}
+
+func collectRuns(runs func(func(start, end uint64) bool)) [][2]uint64 {
+	var got [][2]uint64
+	runs(func(start, end uint64) bool {
+		got = append(got, [2]uint64{start, end})
+		return true
+	})
+	return got
+}
+
+func TestRuns(t *testing.T) {
+	addRange := func(els []uint64, start, end uint64) []uint64 {
+		for e := start; e <= end; e++ {
+			els = append(els, e)
+		}
+		return els
+	}
+	// Runs that cross word and leaf boundaries, fill whole words, or stand alone.
+	var els []uint64
+	els = addRange(els, 0, 2)
+	els = addRange(els, 60, 70)
+	els = addRange(els, 128, 191)
+	els = addRange(els, 250, 600)
+	els = append(els, 1000, 1002, 1<<16-1, 1<<16, 1<<40)
+	for i := 0; i < 300; i++ {
+		els = append(els, uint64(rand.Intn(4000)))
+	}
+	want := naiveRuns(uDedupSort(els))
+
+	d := NewDense(4000)
+	for _, e := range els {
+		if e < 4000 {
+			d.Add(uint(e))
+		}
+	}
+	if got, want := collectRuns(d.Runs), naiveRuns(denseElts64(d)); !cmp.Equal(got, want) {
+		t.Errorf("Dense: got %v, want %v", got, want)
+	}
+
+	if got := collectRuns(sparseFrom(els...).Runs); !cmp.Equal(got, want) {
+		t.Errorf("Sparse: got %v, want %v", got, want)
+	}
+	top := sparseFrom(math.MaxUint64-300, math.MaxUint64-1, math.MaxUint64)
+	if got, want := collectRuns(top.Runs), [][2]uint64{{math.MaxUint64 - 300, math.MaxUint64 - 300}, {math.MaxUint64 - 1, math.MaxUint64}}; !cmp.Equal(got, want) {
+		t.Errorf("Sparse top: got %v, want %v", got, want)
+	}
+	if got := collectRuns(NewSparse().Runs); got != nil {
+		t.Errorf("empty Sparse: got %v", got)
+	}
+
+	for _, s := range []Set64{0, sampleSet64(), Set64(math.MaxUint64), Set64From(0, 1, 2, 5, 62, 63)} {
+		var els []uint64
+		for e := 0; e < 64; e++ {
+			if s.Contains(uint8(e)) {
+				els = append(els, uint64(e))
+			}
+		}
+		if got, want := collectRuns(s.Runs), naiveRuns(els); !cmp.Equal(got, want) {
+			t.Errorf("Set64 %s: got %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestRunsStop(t *testing.T) {
+	s := sparseFrom(1, 2, 5, 6, 9)
+	var got [][2]uint64
+	s.Runs(func(start, end uint64) bool {
+		got = append(got, [2]uint64{start, end})
+		return len(got) < 2
+	})
+	if want := [][2]uint64{{1, 2}, {5, 6}}; !cmp.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func denseElts64(d *Dense) []uint64 {
+	var els []uint64
+	for _, e := range denseElts(d) {
+		els = append(els, uint64(e))
+	}
+	return els
+}
@@ -1,6 +1,7 @@
 package bitset
 
 import (
+	"math/bits"
 	"strconv"
 	"strings"
 )
@@ -22,15 +23,24 @@ func (s *set256) add(n uint8) {
 	s.sets[n/64].Add(n % 64)
 }
 
-func (s *set256) add64(e uint64) { s.add(uint8(e)) }
+func (s *set256) add64(e uint64) bool {
+	if s.contains(uint8(e)) {
+		return false
+	}
+	s.add(uint8(e))
+	return true
+}
 
 func (s *set256) remove(n uint8) {
 	s.sets[n/64].Remove(n % 64)
 }
 
 func (s *set256) remove64(e uint64) bool {
+	if !s.contains(uint8(e)) {
+		return false
+	}
 	s.remove(uint8(e))
-	return s.empty()
+	return true
 }
 
 func (s *set256) contains(n uint8) bool {
@@ -47,6 +57,27 @@ func (s *set256) len() int {
 	return s.sets[0].Len() + s.sets[1].Len() + s.sets[2].Len() + s.sets[3].Len()
 }
 
+func (s *set256) rank(e uint64) int {
+	n := uint8(e)
+	r := 0
+	for _, t := range s.sets[:n/64] {
+		r += t.Len()
+	}
+	return r + (s.sets[n/64] & wordMask(0, 0, uint64(n%64))).Len()
+}
+
+func (s *set256) nth(k int) uint64 {
+	for i, t := range s.sets {
+		if l := t.Len(); k >= l {
+			k -= l
+		} else {
+			e, _ := t.Select(k)
+			return uint64(64*i) + uint64(e)
+		}
+	}
+	panic("bitset: set256.nth: k out of range")
+}
+
 func (s1 *set256) equal(b subber) bool {
 	s2 := b.(*set256)
 	return s1.sets[0] == s2.sets[0] &&
@@ -83,6 +114,60 @@ func (s1 *set256) addIn(sub subber) {
 	s1.sets[3].AddIn(s2.sets[3])
 }
 
+func (s1 *set256) isSubsetOf(sub subber) bool {
+	s2 := sub.(*set256)
+	return s1.sets[0].IsSubsetOf(s2.sets[0]) &&
+		s1.sets[1].IsSubsetOf(s2.sets[1]) &&
+		s1.sets[2].IsSubsetOf(s2.sets[2]) &&
+		s1.sets[3].IsSubsetOf(s2.sets[3])
+}
+
+func (s1 *set256) intersects(sub subber) bool {
+	s2 := sub.(*set256)
+	return s1.sets[0].Intersects(s2.sets[0]) ||
+		s1.sets[1].Intersects(s2.sets[1]) ||
+		s1.sets[2].Intersects(s2.sets[2]) ||
+		s1.sets[3].Intersects(s2.sets[3])
+}
+
+func (s1 *set256) union(sub subber) subber {
+	s2 := sub.(*set256)
+	return &set256{sets: [4]Set64{
+		s1.sets[0] | s2.sets[0],
+		s1.sets[1] | s2.sets[1],
+		s1.sets[2] | s2.sets[2],
+		s1.sets[3] | s2.sets[3],
+	}}
+}
+
+func (s1 *set256) intersection(sub subber) subber {
+	s2 := sub.(*set256)
+	r := &set256{sets: [4]Set64{
+		s1.sets[0] & s2.sets[0],
+		s1.sets[1] & s2.sets[1],
+		s1.sets[2] & s2.sets[2],
+		s1.sets[3] & s2.sets[3],
+	}}
+	if r.empty() {
+		return nil
+	}
+	return r
+}
+
+func (s1 *set256) difference(sub subber) subber {
+	s2 := sub.(*set256)
+	r := &set256{sets: [4]Set64{
+		s1.sets[0] &^ s2.sets[0],
+		s1.sets[1] &^ s2.sets[1],
+		s1.sets[2] &^ s2.sets[2],
+		s1.sets[3] &^ s2.sets[3],
+	}}
+	if r.empty() {
+		return nil
+	}
+	return r
+}
+
 func (s1 *set256) removeIn(sub subber) (empty bool) {
 	s2 := sub.(*set256)
 	s1.sets[0].RemoveIn(s2.sets[0])
@@ -101,6 +186,23 @@ func (s1 *set256) removeNotIn(sub subber) (empty bool) {
 	return s1.empty()
 }
 
+func (s1 *set256) xorIn(sub subber) (empty bool) {
+	s2 := sub.(*set256)
+	s1.sets[0].XorIn(s2.sets[0])
+	s1.sets[1].XorIn(s2.sets[1])
+	s1.sets[2].XorIn(s2.sets[2])
+	s1.sets[3].XorIn(s2.sets[3])
+	return s1.empty()
+}
+
+func (s1 *set256) lenIntersection(sub subber) int {
+	s2 := sub.(*set256)
+	return (s1.sets[0] & s2.sets[0]).Len() +
+		(s1.sets[1] & s2.sets[1]).Len() +
+		(s1.sets[2] & s2.sets[2]).Len() +
+		(s1.sets[3] & s2.sets[3]).Len()
+}
+
 func (s *set256) elements(f func([]uint64) bool, offset uint64) bool {
 	var buf [64]uint64
 	for i, ss := range s.sets {
@@ -116,6 +218,102 @@ func (s *set256) elements(f func([]uint64) bool, offset uint64) bool {
 	return true
 }
 
+func (s *set256) elementsReverse(f func([]uint64) bool, offset uint64) bool {
+	var buf [64]uint64
+	for i := len(s.sets) - 1; i >= 0; i-- {
+		n := s.sets[i].populate64Reverse(&buf)
+		offset2 := offset + uint64(64*i)
+		for j := range buf[:n] {
+			buf[j] += offset2
+		}
+		if !f(buf[:n]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *set256) elementsRange(f func([]uint64) bool, offset, lo, hi uint64) bool {
+	// The range overlaps s, so lo < offset+256 and hi > offset.
+	l, h := uint64(0), uint64(256)
+	if lo > offset {
+		l = lo - offset
+	}
+	if hi-offset < h {
+		h = hi - offset
+	}
+	var buf [64]uint64
+	for i := l / 64; i <= (h-1)/64; i++ {
+		t := s.sets[i] & wordMask(i, l, h)
+		if t == 0 {
+			continue
+		}
+		n := t.populate64(&buf)
+		offset2 := offset + 64*i
+		for j := range buf[:n] {
+			buf[j] += offset2
+		}
+		if !f(buf[:n]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *set256) runs(r *runBuilder, offset uint64) bool {
+	for i, t := range s.sets {
+		if !r.word(offset+uint64(64*i), uint64(t)) {
+			return false
+		}
+	}
+	return true
+}
+
+// next returns the smallest element of s that is at least lo. The second return
+// value is false if there is no such element.
+func (s *set256) next(lo uint8) (uint8, bool) {
+	i := int(lo / 64)
+	if w := uint64(s.sets[i]) >> (lo % 64); w != 0 {
+		return lo + uint8(bits.TrailingZeros64(w)), true
+	}
+	for i++; i < len(s.sets); i++ {
+		if w := uint64(s.sets[i]); w != 0 {
+			return uint8(64*i + bits.TrailingZeros64(w)), true
+		}
+	}
+	return 0, false
+}
+
+// prev returns the largest element of s that is at most hi. The second return
+// value is false if there is no such element.
+func (s *set256) prev(hi uint8) (uint8, bool) {
+	i := int(hi / 64)
+	if w := uint64(s.sets[i]) << (63 - hi%64); w != 0 {
+		return hi - uint8(bits.LeadingZeros64(w)), true
+	}
+	for i--; i >= 0; i-- {
+		if w := uint64(s.sets[i]); w != 0 {
+			return uint8(64*i + 63 - bits.LeadingZeros64(w)), true
+		}
+	}
+	return 0, false
+}
+
+// indexes calls f on each element of s, from lowest to highest, until f returns
+// false.
+func (s *set256) indexes(f func(uint8) bool) bool {
+	for i, t := range s.sets {
+		w := uint64(t)
+		for w != 0 {
+			if !f(uint8(64*i + bits.TrailingZeros64(w))) {
+				return false
+			}
+			w &= w - 1
+		}
+	}
+	return true
+}
+
 func (s set256) String() string {
 	var b strings.Builder
 	b.WriteByte('{')
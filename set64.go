@@ -63,6 +63,26 @@ func (s1 Set64) Equal(s2 Set64) bool {
 	return s1 == s2
 }
 
+// IsSubsetOf reports whether every element of s1 is in s2.
+func (s1 Set64) IsSubsetOf(s2 Set64) bool {
+	return s1&^s2 == 0
+}
+
+// IsSupersetOf reports whether every element of s2 is in s1.
+func (s1 Set64) IsSupersetOf(s2 Set64) bool {
+	return s2.IsSubsetOf(s1)
+}
+
+// Intersects reports whether s1 and s2 have an element in common.
+func (s1 Set64) Intersects(s2 Set64) bool {
+	return s1&s2 != 0
+}
+
+// IsDisjoint reports whether s1 and s2 have no elements in common.
+func (s1 Set64) IsDisjoint(s2 Set64) bool {
+	return !s1.Intersects(s2)
+}
+
 // Complement replaces s with its complement.
 func (s *Set64) Complement() {
 	*s = ^*s
@@ -87,18 +107,86 @@ func (s1 *Set64) RemoveNotIn(s2 Set64) {
 	*s1 &= s2
 }
 
-// append appends the elements of s to elts, in ascending order.
-func (s Set64) append(elts []uint8) []uint8 {
-	low, high := s.elementRange()
-	for e := low; e < high; e++ {
-		u := uint8(e)
-		if s.Contains(u) {
-			elts = append(elts, u)
-		}
+// XorIn removes from s1 the elements that are in s2, and adds those of s2 that
+// were not in s1. It sets s1 to the symmetric difference of s1 and s2.
+func (s1 *Set64) XorIn(s2 Set64) {
+	*s1 ^= s2
+}
+
+// AppendTo appends the elements of s to elts, in ascending order, and returns
+// the result.
+func (s Set64) AppendTo(elts []uint8) []uint8 {
+	for w := uint64(s); w != 0; w &= w - 1 { // remove the smallest element
+		elts = append(elts, uint8(bits.TrailingZeros64(w)))
 	}
 	return elts
 }
 
+// Elements calls f on the elements of s, if any, in a single slice, from lowest
+// to highest. The slice passed to f will be reused when f returns.
+func (s Set64) Elements(f func([]uint8) bool) {
+	var buf [64]uint8
+	if elts := s.AppendTo(buf[:0]); len(elts) > 0 {
+		f(elts)
+	}
+}
+
+// Min returns the smallest element of s. If s is empty, Min returns 0, false.
+func (s Set64) Min() (uint8, bool) {
+	// TrailingZeros64 is 64 for an empty set, and 64&63 is 0.
+	return uint8(bits.TrailingZeros64(uint64(s)) & 63), s != 0
+}
+
+// Max returns the largest element of s. If s is empty, Max returns 0, false.
+func (s Set64) Max() (uint8, bool) {
+	n := bits.Len64(uint64(s))
+	// Shifting -n right by one less than its size gives all ones if n > 0,
+	// and zero otherwise.
+	return uint8((n - 1) & (-n >> (bits.UintSize - 1))), s != 0
+}
+
+// Next returns the smallest element of s that is greater than after. If there
+// is none, Next returns 0, false.
+func (s Set64) Next(after uint8) (uint8, bool) {
+	// 2<<after is 0 when after is 63, so the mask covers all of s.
+	return (s &^ (2<<after - 1)).Min()
+}
+
+// Prev returns the largest element of s that is less than before. If there is
+// none, Prev returns 0, false.
+func (s Set64) Prev(before uint8) (uint8, bool) {
+	return (s & (1<<before - 1)).Max()
+}
+
+// Rank returns the number of elements of s that are less than n.
+func (s Set64) Rank(n uint8) int {
+	pos, _ := s.position(n)
+	return pos
+}
+
+// Select returns the k'th smallest element of s, counting from zero, so that
+// s.Rank(s.Select(k)) == k. If k is negative or not less than s.Len(), Select
+// returns 0, false.
+func (s Set64) Select(k int) (uint8, bool) {
+	w := uint64(s)
+	if k < 0 || k >= bits.OnesCount64(w) {
+		return 0, false
+	}
+	// Narrow the search to halves of decreasing width. At each step, move to
+	// the upper half if the lower one has at most r elements, without
+	// branching.
+	r := uint(k)
+	pos := uint(0)
+	for _, width := range [...]uint{32, 16, 8, 4, 2, 1} {
+		c := uint(bits.OnesCount64(w & (1<<width - 1)))
+		upper := 1 - (r-c)>>(bits.UintSize-1) // 1 if r >= c, else 0
+		w >>= width * upper
+		pos += width * upper
+		r -= c * upper
+	}
+	return uint8(pos), true
+}
+
 func (s Set64) populate(b *[64]uint) int {
 	low, high := s.elementRange()
 	i := 0
@@ -123,6 +211,60 @@ func (s Set64) populate64(b *[64]uint64) int {
 	return i
 }
 
+// fillReverse stores the elements of s in b in descending order, and returns
+// their number.
+func fillReverse[T uint8 | uint | uint64](s Set64, b *[64]T) int {
+	w := uint64(s)
+	i := 0
+	for w != 0 {
+		e := 63 - bits.LeadingZeros64(w)
+		(*b)[i] = T(e)
+		i++
+		w &^= 1 << e
+	}
+	return i
+}
+
+// populateReverse is like populate, but stores the elements in descending order.
+func (s Set64) populateReverse(b *[64]uint) int { return fillReverse(s, b) }
+
+// populate64Reverse is like populate64, but stores the elements in descending
+// order.
+func (s Set64) populate64Reverse(b *[64]uint64) int { return fillReverse(s, b) }
+
+// wordMask returns the bits of the i'th word in a sequence of Set64s that
+// represent the elements in [lo, hi).
+func wordMask(i, lo, hi uint64) Set64 {
+	start, end := 64*i, 64*i+64
+	if lo > start {
+		start = lo
+	}
+	if hi < end {
+		end = hi
+	}
+	if start >= end {
+		return 0
+	}
+	return Set64((uint64(1)<<(end-64*i) - 1) &^ (uint64(1)<<(start-64*i) - 1))
+}
+
+// ElementsReverse calls f on the elements of s, if any, in a single slice, from
+// highest to lowest. The slice passed to f will be reused when f returns.
+func (s Set64) ElementsReverse(f func([]uint8) bool) {
+	var buf [64]uint8
+	if n := fillReverse(s, &buf); n > 0 {
+		f(buf[:n])
+	}
+}
+
+// elements64 calls f on the elements of s, if any, in a single slice.
+func (s Set64) elements64(f func([]uint64) bool) {
+	var buf [64]uint64
+	if n := s.populate64(&buf); n > 0 {
+		f(buf[:n])
+	}
+}
+
 func (s Set64) elementRange() (int, int) {
 	return bits.TrailingZeros64(uint64(s)), 64 - bits.LeadingZeros64(uint64(s))
 }
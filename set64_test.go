@@ -2,6 +2,8 @@ package bitset
 
 import (
 	"math"
+	"math/rand"
+	"sort"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -69,12 +71,12 @@ func TestSet64LenEmpty(t *testing.T) {
 
 func TestAppend(t *testing.T) {
 	s := Set64From(3, 17, 63)
-	got := s.append(nil)
+	got := s.AppendTo(nil)
 	want := []uint8{3, 17, 63}
 	if !cmp.Equal(got, want) {
 		t.Errorf("%s: got %v, want %v", s, got, want)
 	}
-	got = s.append([]uint8{100})
+	got = s.AppendTo([]uint8{100})
 	want = []uint8{100, 3, 17, 63}
 	if !cmp.Equal(got, want) {
 		t.Errorf("%s: got %v, want %v", s, got, want)
@@ -117,3 +119,103 @@ func naiveElementsUint8(s Set64) []uint8 {
 	}
 	return els
 }
+
+func TestSet64Queries(t *testing.T) {
+	sets := []Set64{0, sampleSet64(), Set64(math.MaxUint64), Set64From(0), Set64From(63), Set64From(1, 2, 40)}
+	for i := 0; i < 20; i++ {
+		sets = append(sets, Set64(rand.Uint64()&rand.Uint64()))
+	}
+	for _, s := range sets {
+		els := naiveElementsUint8(s)
+		var got []uint8
+		s.Elements(func(e []uint8) bool {
+			got = append(got, e...)
+			return true
+		})
+		if !cmp.Equal(got, els) {
+			t.Errorf("%s: Elements: got %v", s, got)
+		}
+
+		check := func(name string, arg int, gotV uint8, gotOK bool, want []uint8) {
+			t.Helper()
+			wantV, wantOK := uint8(0), len(want) > 0
+			if wantOK {
+				wantV = want[0]
+			}
+			if gotV != wantV || gotOK != wantOK {
+				t.Errorf("%s: %s(%d) = (%d, %t), want (%d, %t)", s, name, arg, gotV, gotOK, wantV, wantOK)
+			}
+		}
+		// reversed returns els in descending order.
+		reversed := func(els []uint8) []uint8 {
+			r := append([]uint8(nil), els...)
+			sort.Slice(r, func(i, j int) bool { return r[i] > r[j] })
+			return r
+		}
+		v, ok := s.Min()
+		check("Min", 0, v, ok, els)
+		v, ok = s.Max()
+		check("Max", 0, v, ok, reversed(els))
+		for n := 0; n < 64; n++ {
+			var above, below []uint8
+			for _, e := range els {
+				if int(e) > n {
+					above = append(above, e)
+				}
+				if int(e) < n {
+					below = append(below, e)
+				}
+			}
+			v, ok := s.Next(uint8(n))
+			check("Next", n, v, ok, above)
+			v, ok = s.Prev(uint8(n))
+			check("Prev", n, v, ok, reversed(below))
+			if got := s.Rank(uint8(n)); got != len(below) {
+				t.Errorf("%s: Rank(%d) = %d, want %d", s, n, got, len(below))
+			}
+		}
+		for k := -1; k <= len(els); k++ {
+			var want []uint8
+			if k >= 0 && k < len(els) {
+				want = els[k:]
+			}
+			v, ok := s.Select(k)
+			check("Select", k, v, ok, want)
+		}
+	}
+}
+
+func TestSet64XorIn(t *testing.T) {
+	s := Set64From(1, 2, 3, 63)
+	s.XorIn(Set64From(2, 3, 4, 0))
+	if want := Set64From(0, 1, 4, 63); s != want {
+		t.Errorf("got %s, want %s", s, want)
+	}
+}
+
+func TestSet64Predicates(t *testing.T) {
+	for _, test := range []struct {
+		s1, s2             Set64
+		subset, intersects bool
+	}{
+		{0, 0, true, false},
+		{0, Set64From(3), true, false},
+		{Set64From(3), 0, false, false},
+		{Set64From(3, 63), Set64From(1, 3, 63), true, true},
+		{Set64From(1, 3, 63), Set64From(3, 63), false, true},
+		{Set64From(1, 2), Set64From(3, 4), false, false},
+	} {
+		if got := test.s1.IsSubsetOf(test.s2); got != test.subset {
+			t.Errorf("%s.IsSubsetOf(%s) = %t", test.s1, test.s2, got)
+		}
+		if got := test.s2.IsSupersetOf(test.s1); got != test.subset {
+			t.Errorf("%s.IsSupersetOf(%s) = %t", test.s2, test.s1, got)
+		}
+		if got := test.s1.Intersects(test.s2); got != test.intersects {
+			t.Errorf("%s.Intersects(%s) = %t", test.s1, test.s2, got)
+		}
+		if got := test.s1.IsDisjoint(test.s2); got == test.intersects {
+			t.Errorf("%s.IsDisjoint(%s) = %t", test.s1, test.s2, got)
+		}
+	}
+}
@@ -38,12 +38,59 @@ func (s *Sparse) Add64(n uint64) {
 	s.root.add64(n)
 }
 
+// addRange adds the elements of [start, end] to s, a leaf at a time.
+func (s *Sparse) addRange(start, end uint64) {
+	if s.root == nil {
+		s.init()
+	}
+	for {
+		n := s.root.descend(start, 8, 0)
+		index := uint8(start >> 8)
+		pos, found := n.bitset.position(index)
+		if !found {
+			n.insertSubnode(pos, subnode{index: index, sub: &set256{}})
+		}
+		leaf := n.subnodes[pos].sub.(*set256)
+		base := start &^ 0xff
+		last := base + 0xff
+		if end < last {
+			last = end
+		}
+		added := 0
+		for i := range leaf.sets {
+			m := wordMask(uint64(i), start-base, last-base+1)
+			added += (m &^ leaf.sets[i]).Len()
+			leaf.sets[i] |= m
+		}
+		// Now that we know how many elements were new, update the counts.
+		s.root.descend(start, 8, added)
+		if last == end {
+			return
+		}
+		start = last + 1
+	}
+}
+
+// rangeLimiter returns a function that reports whether adding the elements of
+// [start, end] would bring the total added so far past decodeLimit.
+func rangeLimiter() func(start, end uint64) bool {
+	total := uint64(0)
+	return func(start, end uint64) bool {
+		n := end - start // one less than the number of elements, to avoid overflow
+		if n >= decodeLimit || total+n >= decodeLimit {
+			return true
+		}
+		total += n + 1
+		return false
+	}
+}
+
 // Remove64 removes n from s.
 func (s *Sparse) Remove64(n uint64) {
 	if s.root == nil {
 		return
 	}
-	if s.root.remove64(uint64(n)) {
+	if s.root.remove64(n) && s.root.count == 0 {
 		s.root = nil
 	}
 }
@@ -56,6 +103,45 @@ func (s *Sparse) Contains64(n uint64) bool {
 	return s.root.contains64(n)
 }
 
+// Min returns the smallest element of s. The second return value is false if s
+// is empty.
+func (s *Sparse) Min() (uint64, bool) {
+	if s.root == nil {
+		return 0, false
+	}
+	return s.root.min(), true
+}
+
+// Max returns the largest element of s. The second return value is false if s
+// is empty.
+func (s *Sparse) Max() (uint64, bool) {
+	if s.root == nil {
+		return 0, false
+	}
+	return s.root.max(), true
+}
+
+// PopMin removes the smallest element of s and returns it. The second return
+// value is false if s is empty. Together with Add64, PopMin lets a Sparse serve
+// as a priority queue of distinct uint64s.
+func (s *Sparse) PopMin() (uint64, bool) {
+	n, ok := s.Min()
+	if ok {
+		s.Remove64(n)
+	}
+	return n, ok
+}
+
+// PopMax removes the largest element of s and returns it. The second return
+// value is false if s is empty.
+func (s *Sparse) PopMax() (uint64, bool) {
+	n, ok := s.Max()
+	if ok {
+		s.Remove64(n)
+	}
+	return n, ok
+}
+
 // Empty reports whether s has no elements.
 func (s *Sparse) Empty() bool {
 	return s.root == nil
@@ -74,14 +160,43 @@ func (s1 *Sparse) Equal(s2 *Sparse) bool {
 	return s1.root.equal(s2.root)
 }
 
+// IsSubsetOf reports whether every element of s1 is in s2.
+func (s1 *Sparse) IsSubsetOf(s2 *Sparse) bool {
+	if s1.root == nil {
+		return true
+	}
+	if s2.root == nil {
+		return false
+	}
+	return s1.root.isSubsetOf(s2.root)
+}
+
+// IsSupersetOf reports whether every element of s2 is in s1.
+func (s1 *Sparse) IsSupersetOf(s2 *Sparse) bool {
+	return s2.IsSubsetOf(s1)
+}
+
+// Intersects reports whether s1 and s2 have an element in common.
+func (s1 *Sparse) Intersects(s2 *Sparse) bool {
+	if s1.root == nil || s2.root == nil {
+		return false
+	}
+	return s1.root.intersects(s2.root)
+}
+
+// IsDisjoint reports whether s1 and s2 have no elements in common.
+func (s1 *Sparse) IsDisjoint(s2 *Sparse) bool {
+	return !s1.Intersects(s2)
+}
+
 // Copy returns a copy of s.
 func (s *Sparse) Copy() *Sparse {
 	c := NewSparse()
-	s.root = s.root.copyNode()
+	c.root = s.root.copyNode()
 	return c
 }
 
-// Len returns the number of elements in s.
+// Len returns the number of elements in s. It takes constant time.
 func (s *Sparse) Len() int {
 	if s.root == nil {
 		return 0
@@ -127,6 +242,53 @@ func (s1 *Sparse) RemoveNotIn(s2 *Sparse) {
 	}
 }
 
+// LenAddIn returns what s1.Len() would be after s1.AddIn(s2), without modifying
+// s1 or allocating.
+func (s1 *Sparse) LenAddIn(s2 *Sparse) int {
+	return s1.Len() + s2.Len() - s1.lenIntersection(s2)
+}
+
+// LenRemoveIn returns what s1.Len() would be after s1.RemoveIn(s2), without
+// modifying s1 or allocating.
+func (s1 *Sparse) LenRemoveIn(s2 *Sparse) int {
+	return s1.Len() - s1.lenIntersection(s2)
+}
+
+// LenRemoveNotIn returns what s1.Len() would be after s1.RemoveNotIn(s2),
+// without modifying s1 or allocating.
+func (s1 *Sparse) LenRemoveNotIn(s2 *Sparse) int {
+	return s1.lenIntersection(s2)
+}
+
+// lenIntersection returns the number of elements in both s1 and s2.
+func (s1 *Sparse) lenIntersection(s2 *Sparse) int {
+	if s1.Empty() || s2.Empty() {
+		return 0
+	}
+	return s1.root.lenIntersection(s2.root)
+}
+
+// XorIn removes from s1 the elements that are in s2, and adds those of s2 that
+// were not in s1. It sets s1 to the symmetric difference of s1 and s2.
+func (s1 *Sparse) XorIn(s2 *Sparse) {
+	if s2.Empty() {
+		return
+	}
+	if s1.Empty() {
+		s1.root = s2.root.copyNode()
+		return
+	}
+	if s1.root.xorIn(s2.root) {
+		s1.root = nil
+	}
+}
+
+// LenXor returns what s1.Len() would be after s1.XorIn(s2), without modifying
+// s1.
+func (s1 *Sparse) LenXor(s2 *Sparse) int {
+	return s1.Len() + s2.Len() - 2*s1.lenIntersection(s2)
+}
+
 // String returns a representation of s in standard set notation.
 func (s *Sparse) String() string {
 	var b strings.Builder
@@ -156,6 +318,28 @@ func (s *Sparse) Elements(f func([]uint64) bool) {
 	s.root.elements(f, 0)
 }
 
+// ElementsReverse calls f on successive slices of the set's elements, from
+// highest to lowest. If f returns false, the iteration stops. The slice passed
+// to f will be reused when f returns.
+func (s *Sparse) ElementsReverse(f func([]uint64) bool) {
+	if s.root == nil {
+		return
+	}
+	s.root.elementsReverse(f, 0)
+}
+
+// ElementsRange calls f on successive slices of the set's elements that are in
+// [lo, hi), from lowest to highest. If f returns false, the iteration stops.
+// The slice passed to f will be reused when f returns.
+func (s *Sparse) ElementsRange(lo, hi uint64, f func([]uint64) bool) {
+	if s.root == nil || lo >= hi {
+		return
+	}
+	s.root.elementsRange(f, 0, lo, hi)
+}
+
+func (s *Sparse) elements64(f func([]uint64) bool) { s.Elements(f) }
+
 func (s *Sparse) memSize() uint64 {
 	sz := memSize(*s)
 	if s.root != nil {
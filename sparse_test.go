@@ -1,6 +1,7 @@
 package bitset
 
 import (
+	"math"
 	"math/rand"
 	"sort"
 	"testing"
@@ -90,6 +91,130 @@ func TestSparseAddIn(t *testing.T) {
 	}
 }
 
+func TestSparseXorIn(t *testing.T) {
+	clustered := func(n int) []uint64 {
+		var els []uint64
+		for i := 0; i < n; i++ {
+			els = append(els, uint64(rand.Intn(1<<12)))
+		}
+		return els
+	}
+	same := []uint64{5, 300, 1 << 40}
+	for _, test := range []struct {
+		in1, in2 []uint64
+	}{
+		{nil, nil},
+		{nil, []uint64{1}},
+		{[]uint64{17, 99}, []uint64{3, 500, 1000}},
+		{same, same},
+		{[]uint64{1, 2, 1 << 40}, []uint64{2, 3, 1 << 40}},
+		{clustered(300), clustered(300)},
+		{uRandSlice(100), append(uRandSlice(100), 0)},
+	} {
+		want := sparseFrom(uDifference(uUnion(test.in1, test.in2), uIntersection(test.in1, test.in2))...)
+		for _, in := range [][2][]uint64{{test.in1, test.in2}, {test.in2, test.in1}} {
+			s1, s2 := sparseFrom(in[0]...), sparseFrom(in[1]...)
+			if got := s1.LenXor(s2); got != want.Len() {
+				t.Errorf("%v, %v: LenXor = %d, want %d", in[0], in[1], got, want.Len())
+			}
+			s1.XorIn(s2)
+			if !s1.Equal(want) {
+				t.Errorf("%v, %v: got %s, want %s", in[0], in[1], s1, want)
+			}
+			checkCounts(t, s1)
+			if !s2.Equal(sparseFrom(in[1]...)) {
+				t.Errorf("%v, %v: argument changed", in[0], in[1])
+			}
+		}
+	}
+}
+
+func TestSparseLenOps(t *testing.T) {
+	clustered := func(n int) []uint64 {
+		var els []uint64
+		for i := 0; i < n; i++ {
+			els = append(els, uint64(rand.Intn(1<<12)))
+		}
+		return els
+	}
+	for _, test := range []struct {
+		in1, in2 []uint64
+	}{
+		{nil, nil},
+		{nil, []uint64{1}},
+		{[]uint64{17, 99}, []uint64{3, 500, 1000}},
+		{[]uint64{1, 2, 1 << 40}, []uint64{2, 3, 1 << 40}},
+		{clustered(300), clustered(300)},
+		{uRandSlice(100), uRandSlice(100)},
+	} {
+		for _, in := range [][2][]uint64{{test.in1, test.in2}, {test.in2, test.in1}} {
+			s1, s2 := sparseFrom(in[0]...), sparseFrom(in[1]...)
+			for _, op := range []struct {
+				name string
+				got  int
+				want []uint64
+			}{
+				{"LenAddIn", s1.LenAddIn(s2), uUnion(in[0], in[1])},
+				{"LenRemoveIn", s1.LenRemoveIn(s2), uDifference(in[0], in[1])},
+				{"LenRemoveNotIn", s1.LenRemoveNotIn(s2), uIntersection(in[0], in[1])},
+			} {
+				if want := len(uDedupSort(op.want)); op.got != want {
+					t.Errorf("%v, %v: %s = %d, want %d", in[0], in[1], op.name, op.got, want)
+				}
+			}
+			if !s1.Equal(sparseFrom(in[0]...)) {
+				t.Errorf("%v, %v: receiver changed", in[0], in[1])
+			}
+		}
+	}
+
+	s1, s2 := sparseFrom(clustered(300)...), sparseFrom(clustered(300)...)
+	allocs := testing.AllocsPerRun(10, func() {
+		s1.LenAddIn(s2)
+		s1.LenRemoveIn(s2)
+		s1.LenRemoveNotIn(s2)
+	})
+	if allocs != 0 {
+		t.Errorf("got %.1f allocations, want 0", allocs)
+	}
+}
+
+func TestSparsePredicates(t *testing.T) {
+	big := append(uRandSlice(100), 1, 2, 300, 1<<40)
+	for _, test := range []struct {
+		in1, in2 []uint64
+	}{
+		{nil, nil},
+		{nil, []uint64{1}},
+		{[]uint64{1}, nil},
+		{[]uint64{17, 99}, []uint64{3, 500, 1000}},
+		{[]uint64{2, 300}, []uint64{1, 2, 3, 300}},
+		{[]uint64{1, 2, 1 << 40}, []uint64{2, 3, 1 << 40}},
+		{[]uint64{1 << 40}, big},
+		{big[:50], big},
+		{append(big[:10:10], 299), big},
+		{uRandSlice(100), uRandSlice(100)},
+	} {
+		for _, in := range [][2][]uint64{{test.in1, test.in2}, {test.in2, test.in1}} {
+			s1, s2 := sparseFrom(in[0]...), sparseFrom(in[1]...)
+			subset := len(uDifference(in[0], in[1])) == 0
+			intersects := len(uIntersection(in[0], in[1])) > 0
+			if got := s1.IsSubsetOf(s2); got != subset {
+				t.Errorf("%v IsSubsetOf %v = %t", in[0], in[1], got)
+			}
+			if got := s2.IsSupersetOf(s1); got != subset {
+				t.Errorf("%v IsSupersetOf %v = %t", in[1], in[0], got)
+			}
+			if got := s1.Intersects(s2); got != intersects {
+				t.Errorf("%v Intersects %v = %t", in[0], in[1], got)
+			}
+			if got := s1.IsDisjoint(s2); got == intersects {
+				t.Errorf("%v IsDisjoint %v = %t", in[0], in[1], got)
+			}
+		}
+	}
+}
+
 func TestSparseRemoveIn(t *testing.T) {
 	for _, test := range []struct {
 		in1, in2 []uint64
@@ -241,6 +366,41 @@ func TestSparseElements(t *testing.T) {
 	}
 }
 
+func TestSparseElementsRange(t *testing.T) {
+	nums := append(uRandSlice(100), 0, 1, 255, 256, 1<<16, 1<<16+1, 1<<40, math.MaxUint64)
+	for i := 0; i < 100; i++ {
+		nums = append(nums, uint64(rand.Intn(1<<18)))
+	}
+	all := uDedupSort(nums)
+	s := sparseFrom(nums...)
+	bounds := append(uRandSlice(20), 0, 1, 255, 256, 257, 1<<16, 1<<18, 1<<40, math.MaxUint64)
+	for i := 0; i < 20; i++ {
+		bounds = append(bounds, uint64(rand.Intn(1<<18)))
+	}
+	for _, lo := range bounds {
+		for _, hi := range bounds {
+			var want []uint64
+			for _, e := range all {
+				if lo <= e && e < hi {
+					want = append(want, e)
+				}
+			}
+			var got []uint64
+			s.ElementsRange(lo, hi, func(e []uint64) bool {
+				got = append(got, e...)
+				return true
+			})
+			if !cmp.Equal(got, want) {
+				t.Fatalf("[%d, %d): got %v, want %v", lo, hi, got, want)
+			}
+		}
+	}
+	NewSparse().ElementsRange(0, math.MaxUint64, func([]uint64) bool {
+		t.Fatal("called f on empty set")
+		return true
+	})
+}
+
 func TestString(t *testing.T) {
 	for _, test := range []struct {
 		els  []uint64
@@ -323,3 +483,36 @@ func uRand() uint64 {
 	hi := uint64(rand.Uint32())
 	return (hi << 32) | lo
 }
+
+func TestSparseMinMax(t *testing.T) {
+	s := NewSparse()
+	if _, ok := s.Max(); ok {
+		t.Error("Max of empty set succeeded")
+	}
+	if _, ok := s.PopMin(); ok {
+		t.Error("PopMin of empty set succeeded")
+	}
+	nums := append(uRandSlice(100), 0, 1, 300, 1<<16, math.MaxUint64)
+	want := uDedupSort(nums)
+	s = sparseFrom(nums...)
+	if got, ok := s.Min(); !ok || got != 0 {
+		t.Errorf("Min = (%d, %t), want (0, true)", got, ok)
+	}
+	if got, ok := s.Max(); !ok || got != math.MaxUint64 {
+		t.Errorf("Max = (%d, %t), want (%d, true)", got, ok, uint64(math.MaxUint64))
+	}
+	for i, j := 0, len(want)-1; i <= j; i, j = i+1, j-1 {
+		if got, ok := s.PopMin(); !ok || got != want[i] {
+			t.Fatalf("PopMin = (%d, %t), want (%d, true)", got, ok, want[i])
+		}
+		if i == j {
+			break
+		}
+		if got, ok := s.PopMax(); !ok || got != want[j] {
+			t.Fatalf("PopMax = (%d, %t), want (%d, true)", got, ok, want[j])
+		}
+	}
+	if !s.Empty() {
+		t.Errorf("%s is not empty", s)
+	}
+}
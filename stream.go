@@ -0,0 +1,304 @@
+package bitset
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The stream encoding of a set of uint64s, version 1, is a version byte
+// followed by a sequence of blocks. Each block is:
+//
+//	count    uvarint, the number of elements in the block
+//	first    uvarint, the smallest element in the block
+//	size     uvarint, the length of the rest of the block in bytes
+//	deltas   count-1 uvarints, the differences between successive elements
+//
+// A block with count 0 and no other fields ends the stream. The elements of
+// each block are larger than those of the blocks before it.
+const (
+	streamVersion   = 1
+	streamBlockSize = 1024    // elements per block written by Encoder
+	streamMaxBlock  = 1 << 20 // largest count accepted by Decoder
+)
+
+// An Encoder writes a set of uint64s to an io.Writer in the stream encoding,
+// without holding more than one block of elements in memory.
+type Encoder struct {
+	w       io.Writer
+	block   []uint64 // elements of the current block
+	buf     []byte
+	header  bool // wrote the version byte
+	started bool // saw at least one element
+	last    uint64
+	err     error
+}
+
+// NewEncoder returns an Encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes the elements of s and then closes the Encoder.
+func (e *Encoder) Encode(s *Sparse) error {
+	s.Elements(func(elts []uint64) bool {
+		return e.Write(elts) == nil
+	})
+	return e.Close()
+}
+
+// Write adds elts to the stream. The elements must be in increasing order, and
+// larger than those of previous calls to Write.
+func (e *Encoder) Write(elts []uint64) error {
+	if e.err != nil {
+		return e.err
+	}
+	for _, x := range elts {
+		if e.started && x <= e.last {
+			e.err = fmt.Errorf("bitset: Encoder.Write: element %d is not larger than %d", x, e.last)
+			return e.err
+		}
+		e.started = true
+		e.last = x
+		e.block = append(e.block, x)
+		if len(e.block) == streamBlockSize {
+			if err := e.flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Close writes any buffered elements and ends the stream. It does not close the
+// underlying io.Writer.
+func (e *Encoder) Close() error {
+	if e.err != nil {
+		return e.err
+	}
+	if err := e.flush(); err != nil {
+		return err
+	}
+	e.buf = append(e.buf[:0], 0)
+	e.write()
+	if e.err == nil {
+		e.err = errors.New("bitset: Encoder is closed")
+		return nil
+	}
+	return e.err
+}
+
+// flush writes the pending elements, if any, as a block, preceded by the version
+// byte if this is the first block.
+func (e *Encoder) flush() error {
+	b := e.buf[:0]
+	if !e.header {
+		b = append(b, streamVersion)
+		e.header = true
+	}
+	if len(e.block) > 0 {
+		var scratch [binary.MaxVarintLen64]byte
+		size := 0
+		for i := 1; i < len(e.block); i++ {
+			size += binary.PutUvarint(scratch[:], e.block[i]-e.block[i-1])
+		}
+		b = appendUvarint(b, uint64(len(e.block)))
+		b = appendUvarint(b, e.block[0])
+		b = appendUvarint(b, uint64(size))
+		for i := 1; i < len(e.block); i++ {
+			b = appendUvarint(b, e.block[i]-e.block[i-1])
+		}
+		e.block = e.block[:0]
+	}
+	e.buf = b
+	e.write()
+	return e.err
+}
+
+func (e *Encoder) write() {
+	if e.err == nil {
+		_, e.err = e.w.Write(e.buf)
+	}
+}
+
+func appendUvarint(b []byte, u uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], u)
+	return append(b, buf[:n]...)
+}
+
+// A Block describes a block of elements in the stream encoding.
+type Block struct {
+	Count int    // the number of elements in the block
+	First uint64 // the smallest element in the block
+}
+
+// A Decoder reads a set of uint64s in the stream encoding from an io.Reader.
+// It can decode the whole stream into a Sparse, pass the elements to a
+// function a block at a time, or skip blocks by looking only at their headers.
+type Decoder struct {
+	r       *bufio.Reader
+	started bool   // read the version byte
+	cur     Block  // the current block
+	size    int    // unread bytes of the current block's deltas
+	read    bool   // the elements of cur have been read
+	done    bool   // read the final block
+	last    uint64 // a lower bound on the largest element seen so far
+}
+
+// NewDecoder returns a Decoder that reads from r. The Decoder may read beyond the
+// end of the stream.
+func NewDecoder(r io.Reader) *Decoder {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &Decoder{r: br, read: true}
+}
+
+// Next advances to the next block and returns its header. If the elements of the
+// previous block were not read with ReadBlock, they are skipped without being
+// decoded. After the last block, Next returns io.EOF.
+func (d *Decoder) Next() (Block, error) {
+	if d.done {
+		return Block{}, io.EOF
+	}
+	if !d.started {
+		v, err := d.r.ReadByte()
+		if err != nil {
+			return Block{}, unexpectedEOF(err)
+		}
+		if v != streamVersion {
+			return Block{}, fmt.Errorf("bitset: unknown stream encoding version %d", v)
+		}
+		d.started = true
+	}
+	if d.size > 0 {
+		if _, err := d.r.Discard(d.size); err != nil {
+			return Block{}, unexpectedEOF(err)
+		}
+		d.size = 0
+	}
+	count, err := d.uvarint()
+	if err != nil {
+		return Block{}, err
+	}
+	if count == 0 {
+		d.done = true
+		return Block{}, io.EOF
+	}
+	if count > streamMaxBlock {
+		return Block{}, fmt.Errorf("bitset: stream block of %d elements is too large", count)
+	}
+	first, err := d.uvarint()
+	if err != nil {
+		return Block{}, err
+	}
+	if d.cur.Count > 0 && first <= d.last {
+		return Block{}, errors.New("bitset: stream blocks are out of order")
+	}
+	size, err := d.uvarint()
+	if err != nil {
+		return Block{}, err
+	}
+	if size < count-1 || size > (count-1)*binary.MaxVarintLen64 {
+		return Block{}, fmt.Errorf("bitset: stream block of %d elements has %d bytes", count, size)
+	}
+	// The smallest possible last element of the block is first+count-1.
+	if first+(count-1) < first {
+		return Block{}, errors.New("bitset: stream block overflows")
+	}
+	d.cur = Block{Count: int(count), First: first}
+	d.size = int(size)
+	d.read = false
+	d.last = first + (count - 1)
+	return d.cur, nil
+}
+
+// ReadBlock appends the elements of the current block to buf and returns the
+// result. It returns an error if called before Next or more than once per block.
+func (d *Decoder) ReadBlock(buf []uint64) ([]uint64, error) {
+	if d.read {
+		return buf, errors.New("bitset: Decoder.ReadBlock called without a current block")
+	}
+	d.read = true
+	e := d.cur.First
+	buf = append(buf, e)
+	remaining := d.size
+	for i := 1; i < d.cur.Count; i++ {
+		delta, n, err := d.uvarintN()
+		if err != nil {
+			return buf, err
+		}
+		remaining -= n
+		if delta == 0 || e+delta < e || remaining < 0 {
+			return buf, errors.New("bitset: invalid stream block")
+		}
+		e += delta
+		buf = append(buf, e)
+	}
+	if remaining != 0 {
+		return buf, errors.New("bitset: invalid stream block")
+	}
+	d.size = 0
+	d.last = e
+	return buf, nil
+}
+
+// Elements calls f on the elements of each remaining block, in order. If f
+// returns false, Elements stops and returns nil. The slice passed to f will be
+// reused when f returns.
+func (d *Decoder) Elements(f func([]uint64) bool) error {
+	var buf []uint64
+	for {
+		if _, err := d.Next(); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		var err error
+		buf, err = d.ReadBlock(buf[:0])
+		if err != nil {
+			return err
+		}
+		if !f(buf) {
+			return nil
+		}
+	}
+}
+
+// Decode adds the elements of the remaining blocks to s.
+func (d *Decoder) Decode(s *Sparse) error {
+	return d.Elements(func(elts []uint64) bool {
+		for _, e := range elts {
+			s.Add64(e)
+		}
+		return true
+	})
+}
+
+func (d *Decoder) uvarint() (uint64, error) {
+	u, _, err := d.uvarintN()
+	return u, err
+}
+
+// uvarintN reads a uvarint and returns it along with the number of bytes read.
+func (d *Decoder) uvarintN() (uint64, int, error) {
+	var u uint64
+	for i := 0; i < binary.MaxVarintLen64; i++ {
+		c, err := d.r.ReadByte()
+		if err != nil {
+			return 0, i, unexpectedEOF(err)
+		}
+		if c < 0x80 {
+			if i == binary.MaxVarintLen64-1 && c > 1 {
+				break
+			}
+			return u | uint64(c)<<(7*i), i + 1, nil
+		}
+		u |= uint64(c&0x7f) << (7 * i)
+	}
+	return 0, 0, errors.New("bitset: stream varint overflows a 64-bit integer")
+}
@@ -0,0 +1,129 @@
+package bitset
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestStreamRoundTrip(t *testing.T) {
+	var consecutive []uint64
+	for i := uint64(0); i < 3000; i++ {
+		consecutive = append(consecutive, 1<<40+i)
+	}
+	for _, els := range [][]uint64{
+		nil,
+		{0},
+		{1<<64 - 1},
+		{0, 1<<64 - 1},
+		uRandSlice(5000),
+		consecutive,
+	} {
+		s := sparseFrom(els...)
+		var buf bytes.Buffer
+		if err := NewEncoder(&buf).Encode(s); err != nil {
+			t.Fatal(err)
+		}
+		got := NewSparse()
+		if err := NewDecoder(bytes.NewReader(buf.Bytes())).Decode(got); err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(s) {
+			t.Errorf("got %s, want %s", got, s)
+		}
+
+		var elts []uint64
+		err := NewDecoder(bytes.NewReader(buf.Bytes())).Elements(func(e []uint64) bool {
+			elts = append(elts, e...)
+			return true
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := uDedupSort(els); !cmp.Equal(elts, want) {
+			t.Errorf("Elements: got %d elements, want %d", len(elts), len(want))
+		}
+	}
+}
+
+func TestStreamSkip(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	for i := uint64(0); i < 5*streamBlockSize; i += 64 {
+		var batch []uint64
+		for j := i; j < i+64; j++ {
+			batch = append(batch, 3*j)
+		}
+		if err := enc.Write(batch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Skip all but the fourth block.
+	dec := NewDecoder(&buf)
+	var got []uint64
+	for i := 0; ; i++ {
+		b, err := dec.Next()
+		if err == io.EOF {
+			if i != 5 {
+				t.Errorf("got %d blocks, want 5", i)
+			}
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.Count != streamBlockSize || b.First != uint64(3*i*streamBlockSize) {
+			t.Errorf("block %d: got %+v", i, b)
+		}
+		if i == 3 {
+			got, err = dec.ReadBlock(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if len(got) != streamBlockSize || got[0] != 3*3*streamBlockSize || got[1] != got[0]+3 {
+		t.Errorf("got %d elements starting %v", len(got), got[:2])
+	}
+}
+
+func TestStreamErrors(t *testing.T) {
+	enc := NewEncoder(ioutil.Discard)
+	if err := enc.Write([]uint64{5, 5}); err == nil {
+		t.Error("Write of duplicate: got nil, want error")
+	}
+
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(sparseFrom(10, 20, 30)); err != nil {
+		t.Fatal(err)
+	}
+	good := buf.Bytes()
+	// good is: version, count 3, first 10, size 2, deltas 10 10, end.
+	if want := []byte{1, 3, 10, 2, 10, 10, 0}; !bytes.Equal(good, want) {
+		t.Fatalf("got %v, want %v", good, want)
+	}
+	for _, test := range []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"version", modify(good, 0, 9)},
+		{"truncated", good[:5]},
+		{"no end", good[:6]},
+		{"zero delta", modify(good, 4, 0)},
+		{"size", modify(good, 3, 3)},
+		{"order", []byte{1, 1, 10, 0, 1, 10, 0, 0}},
+		{"overflow", []byte{1, 2, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 1, 1, 1, 0}},
+	} {
+		if err := NewDecoder(bytes.NewReader(test.data)).Decode(NewSparse()); err == nil {
+			t.Errorf("%s: got nil, want error", test.name)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package bitset
+
+import (
+	"encoding"
+	"fmt"
+	"strconv"
+)
+
+// TextOptions controls how sets are encoded in set notation. The zero value
+// produces the same output as the String methods.
+type TextOptions struct {
+	// Ranges writes each run of three or more consecutive elements as
+	// "start-end", as in "{1-5, 9}".
+	Ranges bool
+}
+
+var (
+	_ encoding.TextMarshaler   = Set64(0)
+	_ encoding.TextUnmarshaler = (*Set64)(nil)
+	_ encoding.TextMarshaler   = (*Dense)(nil)
+	_ encoding.TextUnmarshaler = (*Dense)(nil)
+	_ encoding.TextMarshaler   = (*Sparse)(nil)
+	_ encoding.TextUnmarshaler = (*Sparse)(nil)
+)
+
+// Marshal returns the representation of set in set notation. The set must be a
+// Set64, *Set64, *Dense or *Sparse.
+func (o TextOptions) Marshal(set interface{}) ([]byte, error) {
+	var es elementSet
+	switch s := set.(type) {
+	case Set64:
+		es = s
+	case *Set64:
+		es = *s
+	case *Dense:
+		es = s
+	case *Sparse:
+		es = s
+	default:
+		return nil, fmt.Errorf("bitset: cannot marshal %T as text", set)
+	}
+	return o.appendText(nil, es), nil
+}
+
+func (o TextOptions) appendText(b []byte, es elementSet) []byte {
+	b = append(b, '{')
+	first := true
+	sep := func() {
+		if !first {
+			b = append(b, ", "...)
+		}
+		first = false
+	}
+	if o.Ranges {
+		es.Runs(func(start, end uint64) bool {
+			sep()
+			b = strconv.AppendUint(b, start, 10)
+			switch {
+			case end == start:
+			case end == start+1:
+				b = append(b, ", "...)
+				b = strconv.AppendUint(b, end, 10)
+			default:
+				b = append(b, '-')
+				b = strconv.AppendUint(b, end, 10)
+			}
+			return true
+		})
+	} else {
+		es.elements64(func(elts []uint64) bool {
+			for _, e := range elts {
+				sep()
+				b = strconv.AppendUint(b, e, 10)
+			}
+			return true
+		})
+	}
+	return append(b, '}')
+}
+
+// String returns a representation of s in standard set notation.
+func (s *Dense) String() string {
+	return string(TextOptions{}.appendText(nil, s))
+}
+
+// MarshalText implements encoding.TextMarshaler. It returns the same
+// representation as String. Use TextOptions to compact runs into ranges.
+func (s Set64) MarshalText() ([]byte, error) { return TextOptions{}.Marshal(s) }
+
+// MarshalText implements encoding.TextMarshaler. It returns the same
+// representation as String. Use TextOptions to compact runs into ranges.
+func (s *Dense) MarshalText() ([]byte, error) { return TextOptions{}.Marshal(s) }
+
+// MarshalText implements encoding.TextMarshaler. It returns the same
+// representation as String. Use TextOptions to compact runs into ranges.
+func (s *Sparse) MarshalText() ([]byte, error) { return TextOptions{}.Marshal(s) }
+
+// UnmarshalText implements encoding.TextUnmarshaler. See ParseSet64.
+func (s *Set64) UnmarshalText(text []byte) error {
+	t, err := ParseSet64(string(text))
+	if err != nil {
+		return err
+	}
+	*s = t
+	return nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. See ParseDense.
+func (s *Dense) UnmarshalText(text []byte) error {
+	t, err := ParseDense(string(text))
+	if err != nil {
+		return err
+	}
+	*s = *t
+	return nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. See ParseSparse.
+func (s *Sparse) UnmarshalText(text []byte) error {
+	t, err := ParseSparse(string(text))
+	if err != nil {
+		return err
+	}
+	*s = *t
+	return nil
+}
+
+// A SyntaxError describes a problem parsing set notation.
+type SyntaxError struct {
+	Offset int    // byte offset in the input at which the problem was found
+	Msg    string // description of the problem
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("bitset: syntax error at offset %d: %s", e.Offset, e.Msg)
+}
+
+// ParseSet64 parses a set written in set notation, like the output of String.
+// Elements are separated by commas and surrounded by braces. An element may
+// also be a range "a-b", meaning all the integers from a to b inclusive.
+// Whitespace is allowed between tokens. Errors are of type *SyntaxError.
+func ParseSet64(s string) (Set64, error) {
+	var set Set64
+	err := parseText(s, func(start, end uint64) string {
+		if end >= 64 {
+			return fmt.Sprintf("%d is too large for a Set64", end)
+		}
+		for e := start; e <= end; e++ {
+			set.Add(uint8(e))
+		}
+		return ""
+	})
+	return set, err
+}
+
+// ParseDense parses a set in the notation accepted by ParseSet64. The capacity
+// of the returned set is the smallest that holds its largest element, which
+// must be less than 2^32, or the largest int on 32-bit platforms.
+func ParseDense(s string) (*Dense, error) {
+	var runs [][2]uint64
+	err := parseText(s, func(start, end uint64) string {
+		if end >= decodeLimit {
+			return fmt.Sprintf("%d is too large for a Dense (limit %d)", end, decodeLimit)
+		}
+		runs = append(runs, [2]uint64{start, end})
+		return ""
+	})
+	if err != nil {
+		return nil, err
+	}
+	d := NewDense(0)
+	if runs != nil {
+		d.sets = setsFromRuns(runs)
+	}
+	return d, nil
+}
+
+// ParseSparse parses a set in the notation accepted by ParseSet64. Its ranges
+// may hold at most 2^32 elements in all, or the largest int on 32-bit
+// platforms.
+func ParseSparse(s string) (*Sparse, error) {
+	set := NewSparse()
+	tooMany := rangeLimiter()
+	err := parseText(s, func(start, end uint64) string {
+		if tooMany(start, end) {
+			return fmt.Sprintf("ranges have more than %d elements", decodeLimit)
+		}
+		set.addRange(start, end)
+		return ""
+	})
+	if err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// parseText parses set notation, calling add with each element or range. If add
+// returns a non-empty string, parsing stops with that as the error message.
+func parseText(s string, add func(start, end uint64) string) error {
+	p := textParser{s: s}
+	p.skipSpace()
+	if err := p.expect('{'); err != nil {
+		return err
+	}
+	p.skipSpace()
+	if p.peek() == '}' {
+		p.pos++
+	} else {
+		for {
+			offset := p.pos
+			start, err := p.number()
+			if err != nil {
+				return err
+			}
+			end := start
+			p.skipSpace()
+			if p.peek() == '-' {
+				p.pos++
+				p.skipSpace()
+				end, err = p.number()
+				if err != nil {
+					return err
+				}
+				if end < start {
+					return &SyntaxError{Offset: offset, Msg: fmt.Sprintf("range %d-%d is backwards", start, end)}
+				}
+				p.skipSpace()
+			}
+			if msg := add(start, end); msg != "" {
+				return &SyntaxError{Offset: offset, Msg: msg}
+			}
+			if p.peek() == '}' {
+				p.pos++
+				break
+			}
+			if err := p.expect(','); err != nil {
+				return p.errorf("expected ',' or '}'")
+			}
+			p.skipSpace()
+		}
+	}
+	p.skipSpace()
+	if p.pos < len(p.s) {
+		return p.errorf("unexpected text after '}'")
+	}
+	return nil
+}
+
+type textParser struct {
+	s   string
+	pos int
+}
+
+func (p *textParser) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Offset: p.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// peek returns the next byte of the input, or 0 at the end.
+func (p *textParser) peek() byte {
+	if p.pos < len(p.s) {
+		return p.s[p.pos]
+	}
+	return 0
+}
+
+func (p *textParser) skipSpace() {
+	for {
+		switch p.peek() {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (p *textParser) expect(c byte) error {
+	if p.peek() != c {
+		return p.errorf("expected %q", c)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *textParser) number() (uint64, error) {
+	start := p.pos
+	for p.pos < len(p.s) && '0' <= p.s[p.pos] && p.s[p.pos] <= '9' {
+		p.pos++
+	}
+	if p.pos == start {
+		return 0, p.errorf("expected a number")
+	}
+	u, err := strconv.ParseUint(p.s[start:p.pos], 10, 64)
+	if err != nil {
+		return 0, &SyntaxError{Offset: start, Msg: fmt.Sprintf("number %s out of range", p.s[start:p.pos])}
+	}
+	return u, nil
+}
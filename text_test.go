@@ -0,0 +1,170 @@
+package bitset
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTextOptions(t *testing.T) {
+	for _, test := range []struct {
+		els            []uint64
+		plain, compact string
+	}{
+		{nil, "{}", "{}"},
+		{[]uint64{5}, "{5}", "{5}"},
+		{[]uint64{1, 2}, "{1, 2}", "{1, 2}"},
+		{[]uint64{0, 1, 2, 3, 9, 20, 21, 22}, "{0, 1, 2, 3, 9, 20, 21, 22}", "{0-3, 9, 20-22}"},
+		{[]uint64{62, 63}, "{62, 63}", "{62, 63}"},
+	} {
+		var s64 Set64
+		d := NewDense(64)
+		for _, e := range test.els {
+			s64.Add(uint8(e))
+			d.Add(uint(e))
+		}
+		for _, set := range []interface{}{s64, d, sparseFrom(test.els...)} {
+			got, err := set.(interface{ MarshalText() ([]byte, error) }).MarshalText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.plain {
+				t.Errorf("%T.MarshalText: got %q, want %q", set, got, test.plain)
+			}
+			if s := set.(interface{ String() string }).String(); s != test.plain {
+				t.Errorf("%T.String: got %q, want %q", set, s, test.plain)
+			}
+			got, err = TextOptions{Ranges: true}.Marshal(set)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.compact {
+				t.Errorf("%T ranges: got %q, want %q", set, got, test.compact)
+			}
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want []uint64
+	}{
+		{"{}", nil},
+		{" { } ", nil},
+		{"{3}", []uint64{3}},
+		{"{1, 2, 3}", []uint64{1, 2, 3}},
+		{"{1,2,3}", []uint64{1, 2, 3}},
+		{"{\n\t3 ,1 , 2\n}\n", []uint64{1, 2, 3}},
+		{"{1-3, 9, 20 - 22}", []uint64{1, 2, 3, 9, 20, 21, 22}},
+		{"{5-5, 4}", []uint64{4, 5}},
+	} {
+		want := sparseFrom(test.want...)
+		sp, err := ParseSparse(test.in)
+		if err != nil {
+			t.Fatalf("%q: %v", test.in, err)
+		}
+		if !sp.Equal(want) {
+			t.Errorf("ParseSparse(%q) = %s, want %s", test.in, sp, want)
+		}
+		s64, err := ParseSet64(test.in)
+		if err != nil {
+			t.Fatalf("%q: %v", test.in, err)
+		}
+		if got := s64.String(); got != want.String() {
+			t.Errorf("ParseSet64(%q) = %s, want %s", test.in, got, want)
+		}
+		var d Dense
+		if err := d.UnmarshalText([]byte(test.in)); err != nil {
+			t.Fatalf("%q: %v", test.in, err)
+		}
+		if got := d.String(); got != want.String() {
+			t.Errorf("Dense.UnmarshalText(%q) = %s, want %s", test.in, got, want)
+		}
+	}
+
+	// Round trip a large set through both forms.
+	want := sparseFrom(uRandSlice(200)...)
+	for _, opts := range []TextOptions{{}, {Ranges: true}} {
+		text, _ := opts.Marshal(want)
+		var got Sparse
+		if err := got.UnmarshalText(text); err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(want) {
+			t.Errorf("got %s, want %s", &got, want)
+		}
+	}
+
+	d, err := ParseDense("{70}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Cap() != 128 {
+		t.Errorf("got capacity %d, want 128", d.Cap())
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, test := range []struct {
+		in     string
+		offset int
+	}{
+		{"", 0},
+		{"1, 2}", 0},
+		{"{1, 2", 5},
+		{"{1 2}", 3},
+		{"{1,}", 3},
+		{"{,1}", 1},
+		{"{-1}", 1},
+		{"{1}x", 3},
+		{"{3-1}", 1},
+		{"{1-}", 3},
+		{"{1, 99999999999999999999}", 4},
+		{"{1, 64}", 4},
+	} {
+		_, err := ParseSet64(test.in)
+		serr, ok := err.(*SyntaxError)
+		if !ok {
+			t.Errorf("%q: got %v, want SyntaxError", test.in, err)
+			continue
+		}
+		if serr.Offset != test.offset {
+			t.Errorf("%q: got offset %d, want %d (%v)", test.in, serr.Offset, test.offset, err)
+		}
+	}
+}
+
+func TestParseLarge(t *testing.T) {
+	for _, test := range []struct {
+		in     string
+		parse  func(string) error
+		offset int
+	}{
+		{"{9000000000000000000}", func(s string) error { _, err := ParseDense(s); return err }, 1},
+		{fmt.Sprintf("{1, %d}", decodeLimit), func(s string) error { _, err := ParseDense(s); return err }, 4},
+		{"{1, 0-18446744073709551615}", func(s string) error { _, err := ParseSparse(s); return err }, 4},
+	} {
+		err := test.parse(test.in)
+		serr, ok := err.(*SyntaxError)
+		if !ok {
+			t.Errorf("%q: got %v, want SyntaxError", test.in, err)
+			continue
+		}
+		if serr.Offset != test.offset {
+			t.Errorf("%q: got offset %d, want %d (%v)", test.in, serr.Offset, test.offset, err)
+		}
+	}
+
+	// A large range is filled a word at a time, not element by element.
+	sp, err := ParseSparse("{0-20000000}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := sp.Len(), 20000001; got != want {
+		t.Errorf("got %d elements, want %d", got, want)
+	}
+	if !sp.Contains64(20000000) || sp.Contains64(20000001) {
+		t.Error("wrong elements at the end of the range")
+	}
+	checkCounts(t, sp)
+}
@@ -0,0 +1,92 @@
+package bitset
+
+import "unsafe"
+
+// A DenseView is an immutable Dense bitset whose bits live in memory it does not
+// own, such as a memory-mapped file. A DenseView never modifies that memory,
+// and the memory must not be modified while the view is in use.
+type DenseView struct {
+	d Dense // never modified
+}
+
+// NewDenseView returns a view of the bitset in words, where bit i of words[j]
+// represents the element 64*j + i. The view shares memory with words.
+func NewDenseView(words []uint64) *DenseView {
+	if len(words) == 0 {
+		return &DenseView{}
+	}
+	// Set64's underlying type is uint64, so the two have the same layout.
+	return &DenseView{d: Dense{sets: unsafe.Slice((*Set64)(unsafe.Pointer(&words[0])), len(words))}}
+}
+
+// NewDenseViewBytes returns a view of data, which must hold the binary encoding
+// of a Dense, as produced by Dense.MarshalBinary or Dense.WriteTo. If data is
+// 8-byte aligned and this machine is little-endian, the view shares memory with
+// data. Otherwise, the view holds a copy of the bits.
+func NewDenseViewBytes(data []byte) (*DenseView, error) {
+	words, err := denseWords(data)
+	if err != nil {
+		return nil, err
+	}
+	nwords := len(words) / 8
+	if nwords == 0 {
+		return &DenseView{}, nil
+	}
+	if !littleEndian || uintptr(unsafe.Pointer(&words[0]))%8 != 0 {
+		var d Dense
+		if err := d.UnmarshalBinary(data); err != nil {
+			return nil, err
+		}
+		return &DenseView{d: d}, nil
+	}
+	sets := unsafe.Slice((*Set64)(unsafe.Pointer(&words[0])), nwords)
+	return &DenseView{d: Dense{sets: sets}}, nil
+}
+
+// littleEndian reports whether this machine stores integers least significant
+// byte first.
+var littleEndian = func() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}()
+
+// Cap returns the maximum number of elements the set can contain,
+// which is one greater than the largest element it can contain.
+func (v *DenseView) Cap() int { return v.d.Cap() }
+
+// Len returns the number of elements in v.
+func (v *DenseView) Len() int { return v.d.Len() }
+
+// Empty reports whether v has no elements.
+func (v *DenseView) Empty() bool { return v.d.Empty() }
+
+// Contains reports whether v contains n.
+func (v *DenseView) Contains(n uint) bool { return v.d.Contains(n) }
+
+// Equal reports whether v has the same elements as s. It may have a different
+// capacity.
+func (v *DenseView) Equal(s *Dense) bool { return v.d.Equal(s) }
+
+// Copy returns a Dense with the same elements and capacity as v, which does not
+// share memory with v.
+func (v *DenseView) Copy() *Dense { return v.d.Copy() }
+
+// Elements calls f on successive slices of the set's elements, from lowest to
+// highest. If f returns false, the iteration stops. The slice passed to f will
+// be reused when f returns.
+func (v *DenseView) Elements(f func([]uint) bool) { v.d.Elements(f) }
+
+// String returns a representation of v in standard set notation.
+func (v *DenseView) String() string { return v.d.String() }
+
+// AddInView adds all the elements in v to s1.
+// It sets s1 to the union of s1 and v.
+func (s1 *Dense) AddInView(v *DenseView) { s1.AddIn(&v.d) }
+
+// RemoveInView removes from s1 all the elements that are in v.
+// It sets s1 to the set difference of s1 and v.
+func (s1 *Dense) RemoveInView(v *DenseView) { s1.RemoveIn(&v.d) }
+
+// RemoveNotInView removes from s1 all the elements that are not in v.
+// It sets s1 to the intersection of s1 and v.
+func (s1 *Dense) RemoveNotInView(v *DenseView) { s1.RemoveNotIn(&v.d) }
@@ -0,0 +1,100 @@
+package bitset
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDenseView(t *testing.T) {
+	words := []uint64{1<<3 | 1<<63, 0, 1 << 2}
+	v := NewDenseView(words)
+	want := []uint{3, 63, 130}
+	var got []uint
+	v.Elements(func(e []uint) bool {
+		got = append(got, e...)
+		return true
+	})
+	if !cmp.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if v.Len() != 3 || v.Cap() != 192 || v.Empty() || !v.Contains(130) || v.Contains(129) {
+		t.Errorf("wrong Len, Cap, Empty or Contains for %s", v)
+	}
+	if d := denseFrom(s{3, 63}); v.Equal(d) {
+		t.Error("equal to a subset")
+	}
+	c := v.Copy()
+	if !v.Equal(c) {
+		t.Errorf("Copy: got %s, want %s", c, v)
+	}
+
+	// The view shares memory with words.
+	words[1] = 1
+	if !v.Contains(64) {
+		t.Error("view does not share memory")
+	}
+	if c.Contains(64) {
+		t.Error("copy shares memory")
+	}
+
+	d := denseFrom(s{3, 4, 64, 65})
+	d.AddInView(v)
+	if want, _ := ParseDense("{3, 4, 63-65, 130}"); !d.Equal(want) {
+		t.Errorf("AddInView: got %s, want %s", d, want)
+	}
+	d = denseFrom(s{3, 4, 64, 65})
+	d.RemoveInView(v)
+	if want := denseFrom(s{4, 65}); !d.Equal(want) {
+		t.Errorf("RemoveInView: got %s, want %s", d, want)
+	}
+	d = denseFrom(s{3, 4, 64, 65})
+	d.RemoveNotInView(v)
+	if want := denseFrom(s{3, 64}); !d.Equal(want) {
+		t.Errorf("RemoveNotInView: got %s, want %s", d, want)
+	}
+	if words[0] != 1<<3|1<<63 || words[1] != 1 || words[2] != 1<<2 {
+		t.Errorf("view memory was modified: %v", words)
+	}
+}
+
+func TestDenseViewBytes(t *testing.T) {
+	d := denseFrom(s{1, 50, 99})
+	enc, err := d.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Copy enc into 8-byte aligned memory, and also one byte past that.
+	backing := make([]uint64, len(enc)/8+1)
+	mem := (*[1 << 20]byte)(unsafe.Pointer(&backing[0]))[: len(backing)*8 : len(backing)*8]
+	aligned := mem[:len(enc)]
+	misaligned := mem[1 : len(enc)+1]
+	copy(aligned, enc)
+	v, err := NewDenseViewBytes(aligned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v.Equal(d) {
+		t.Errorf("got %s, want %s", v, d)
+	}
+	if littleEndian {
+		aligned[denseHeaderSize] |= 1 << 2
+		if !v.Contains(2) {
+			t.Error("aligned view does not share memory")
+		}
+	}
+
+	copy(misaligned, enc)
+	v, err = NewDenseViewBytes(misaligned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v.Equal(d) {
+		t.Errorf("misaligned: got %s, want %s", v, d)
+	}
+
+	if _, err := NewDenseViewBytes(enc[:len(enc)-1]); err == nil {
+		t.Error("got nil, want error")
+	}
+}